@@ -0,0 +1,143 @@
+package plumbing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/loggregator/logging"
+)
+
+// ConsulFinder discovers Doppler gRPC addresses by long-polling the
+// Consul catalog for a named, passing service, emitting an Event
+// whenever the instance set changes.
+type ConsulFinder struct {
+	consulAddr  string
+	serviceName string
+	grpcPort    uint16
+	httpClient  *http.Client
+	log         *logging.Logger
+
+	events  chan Event
+	current []string
+	index   string
+}
+
+// consulServiceEntry mirrors the subset of Consul's
+// /v1/health/service/:service response this finder needs.
+type consulServiceEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Checks []struct {
+		Status string `json:"Status"`
+	} `json:"Checks"`
+}
+
+// NewConsulFinder builds a ConsulFinder that long-polls consulAddr (e.g.
+// "http://127.0.0.1:8500") for serviceName, pairing each healthy
+// instance's address with grpcPort. log is scoped with a
+// "component":"consul_finder" key; a nil log falls back to
+// logging.NewDefault().
+func NewConsulFinder(consulAddr, serviceName string, grpcPort uint16, log *logging.Logger) *ConsulFinder {
+	if log == nil {
+		log = logging.NewDefault()
+	}
+
+	return &ConsulFinder{
+		consulAddr:  consulAddr,
+		serviceName: serviceName,
+		grpcPort:    grpcPort,
+		httpClient:  &http.Client{Timeout: 70 * time.Second},
+		log:         log.With("component", "consul_finder", "service_name", serviceName),
+		events:      make(chan Event, 1),
+	}
+}
+
+// Start fetches the current instance set once synchronously, so the
+// first Next() call has data immediately, then continues long-polling
+// Consul for changes on a goroutine.
+func (f *ConsulFinder) Start() {
+	f.pollAndEmit()
+	go func() {
+		for {
+			f.pollAndEmit()
+		}
+	}()
+}
+
+// Next blocks until the instance set has changed since the last Next
+// call, then returns the new set.
+func (f *ConsulFinder) Next() Event {
+	return <-f.events
+}
+
+func (f *ConsulFinder) pollAndEmit() {
+	u := fmt.Sprintf(
+		"%s/v1/health/service/%s?passing=1&index=%s&wait=60s",
+		f.consulAddr, url.PathEscape(f.serviceName), f.index,
+	)
+
+	resp, err := f.httpClient.Get(u)
+	if err != nil {
+		f.log.Warn("consul long-poll failed", "error", err)
+		time.Sleep(time.Second)
+		return
+	}
+	defer resp.Body.Close()
+
+	if idx := resp.Header.Get("X-Consul-Index"); idx != "" {
+		f.index = idx
+	}
+
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		f.log.Warn("consul response decode failed", "error", err)
+		time.Sleep(time.Second)
+		return
+	}
+
+	addrs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !allChecksPassing(entry) {
+			continue
+		}
+		addrs = append(addrs, fmt.Sprintf("%s:%d", entry.Node.Address, f.grpcPort))
+	}
+	sort.Strings(addrs)
+
+	if equalStrings(addrs, f.current) {
+		return
+	}
+
+	f.log.Info("doppler instance set changed", "addrs", addrs)
+	f.current = addrs
+
+	select {
+	case f.events <- Event{GRPCDopplers: addrs}:
+	default:
+		select {
+		case <-f.events:
+		default:
+		}
+		f.events <- Event{GRPCDopplers: addrs}
+	}
+}
+
+// allChecksPassing reports whether entry is healthy. An instance with no
+// checks at all has never been health-checked, not confirmed healthy, so
+// it does not count as passing.
+func allChecksPassing(entry consulServiceEntry) bool {
+	if len(entry.Checks) == 0 {
+		return false
+	}
+	for _, check := range entry.Checks {
+		if check.Status != "passing" {
+			return false
+		}
+	}
+	return true
+}
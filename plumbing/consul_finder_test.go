@@ -0,0 +1,96 @@
+package plumbing_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/loggregator/plumbing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConsulFinder", func() {
+	Context("given a Consul catalog with one passing and one failing instance", func() {
+		It("emits an Event containing only the passing instance", func() {
+			consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Consul-Index", "1")
+				fmt.Fprint(w, `[
+					{"Node": {"Address": "10.0.0.1"}, "Checks": [{"Status": "passing"}]},
+					{"Node": {"Address": "10.0.0.2"}, "Checks": [{"Status": "critical"}]}
+				]`)
+			}))
+			defer consul.Close()
+
+			f := plumbing.NewConsulFinder(consul.URL, "doppler", 8082, nil)
+			f.Start()
+
+			Expect(f.Next()).To(Equal(plumbing.Event{
+				GRPCDopplers: []string{"10.0.0.1:8082"},
+			}))
+		})
+	})
+
+	Context("given an instance with no health checks at all", func() {
+		It("excludes it, since an unchecked instance is not confirmed healthy", func() {
+			consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Consul-Index", "1")
+				fmt.Fprint(w, `[
+					{"Node": {"Address": "10.0.0.1"}, "Checks": [{"Status": "passing"}]},
+					{"Node": {"Address": "10.0.0.2"}, "Checks": []}
+				]`)
+			}))
+			defer consul.Close()
+
+			f := plumbing.NewConsulFinder(consul.URL, "doppler", 8082, nil)
+			f.Start()
+
+			Expect(f.Next()).To(Equal(plumbing.Event{
+				GRPCDopplers: []string{"10.0.0.1:8082"},
+			}))
+		})
+	})
+
+	Context("given Consul's blocking query reports a new X-Consul-Index after a change", func() {
+		It("long-polls with the updated index and emits a second Event for the new instance set", func() {
+			requestIndexes := make(chan string, 10)
+
+			consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				index := r.URL.Query().Get("index")
+				// The finder keeps polling after the second Event; never
+				// block a handler, or the deferred Close would wait on it.
+				select {
+				case requestIndexes <- index:
+				default:
+				}
+
+				if index == "" || index == "0" {
+					w.Header().Set("X-Consul-Index", "1")
+					fmt.Fprint(w, `[{"Node": {"Address": "10.0.0.1"}, "Checks": [{"Status": "passing"}]}]`)
+					return
+				}
+
+				w.Header().Set("X-Consul-Index", "2")
+				fmt.Fprint(w, `[
+					{"Node": {"Address": "10.0.0.1"}, "Checks": [{"Status": "passing"}]},
+					{"Node": {"Address": "10.0.0.2"}, "Checks": [{"Status": "passing"}]}
+				]`)
+			}))
+			defer consul.Close()
+
+			f := plumbing.NewConsulFinder(consul.URL, "doppler", 8082, nil)
+			f.Start()
+
+			Expect(f.Next()).To(Equal(plumbing.Event{
+				GRPCDopplers: []string{"10.0.0.1:8082"},
+			}))
+			Expect(f.Next()).To(Equal(plumbing.Event{
+				GRPCDopplers: []string{"10.0.0.1:8082", "10.0.0.2:8082"},
+			}))
+
+			Expect(<-requestIndexes).To(BeEmpty())
+			Expect(<-requestIndexes).To(Equal("1"))
+		})
+	})
+})
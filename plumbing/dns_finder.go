@@ -0,0 +1,135 @@
+package plumbing
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/loggregator/logging"
+)
+
+// DNSFinder discovers Doppler gRPC addresses by periodically resolving
+// an SRV record (e.g. _doppler._grpc.service.consul), emitting an Event
+// whenever the resolved address set changes.
+type DNSFinder struct {
+	srvName      string
+	pollInterval time.Duration
+	grpcPort     uint16
+	resolver     func(name string) ([]*net.SRV, error)
+	log          *logging.Logger
+
+	events  chan Event
+	current []string
+}
+
+// DNSFinderOption configures optional DNSFinder behavior.
+type DNSFinderOption func(*DNSFinder)
+
+// WithDNSPollInterval sets how often the SRV record is re-resolved.
+// Defaults to 5s.
+func WithDNSPollInterval(d time.Duration) DNSFinderOption {
+	return func(f *DNSFinder) { f.pollInterval = d }
+}
+
+// WithDNSResolver overrides SRV lookups, primarily for tests that stand
+// up an in-process DNS server.
+func WithDNSResolver(resolver func(name string) ([]*net.SRV, error)) DNSFinderOption {
+	return func(f *DNSFinder) { f.resolver = resolver }
+}
+
+// NewDNSFinder builds a DNSFinder that resolves srvName and pairs each
+// returned host with grpcPort. log is scoped with a
+// "component":"dns_finder" key; a nil log falls back to
+// logging.NewDefault().
+func NewDNSFinder(srvName string, grpcPort uint16, log *logging.Logger, opts ...DNSFinderOption) *DNSFinder {
+	if log == nil {
+		log = logging.NewDefault()
+	}
+
+	f := &DNSFinder{
+		srvName:      srvName,
+		grpcPort:     grpcPort,
+		pollInterval: 5 * time.Second,
+		log:          log.With("component", "dns_finder", "srv_name", srvName),
+		events:       make(chan Event, 1),
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if f.resolver == nil {
+		f.resolver = func(name string) ([]*net.SRV, error) {
+			_, srvs, err := net.LookupSRV("", "", name)
+			return srvs, err
+		}
+	}
+
+	return f
+}
+
+// Start resolves the SRV record once synchronously, so the first Next()
+// call has data immediately, then continues polling on a goroutine.
+func (f *DNSFinder) Start() {
+	f.resolveAndEmit()
+	go func() {
+		ticker := time.NewTicker(f.pollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			f.resolveAndEmit()
+		}
+	}()
+}
+
+// Next blocks until the address set has changed since the last Next
+// call, then returns the new set.
+func (f *DNSFinder) Next() Event {
+	return <-f.events
+}
+
+func (f *DNSFinder) resolveAndEmit() {
+	srvs, err := f.resolver(f.srvName)
+	if err != nil {
+		f.log.Warn("failed to resolve SRV record", "error", err)
+		return
+	}
+
+	addrs := make([]string, 0, len(srvs))
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		addrs = append(addrs, fmt.Sprintf("%s:%d", host, f.grpcPort))
+	}
+	sort.Strings(addrs)
+
+	if equalStrings(addrs, f.current) {
+		return
+	}
+
+	f.log.Info("doppler address set changed", "addrs", addrs)
+	f.current = addrs
+
+	select {
+	case f.events <- Event{GRPCDopplers: addrs}:
+	default:
+		// Drain the stale event so the latest set always wins.
+		select {
+		case <-f.events:
+		default:
+		}
+		f.events <- Event{GRPCDopplers: addrs}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package plumbing_test
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/loggregator/plumbing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DNSFinder", func() {
+	Context("given an SRV record that resolves to two hosts", func() {
+		It("emits an Event pairing each host with the gRPC port", func() {
+			resolver := func(name string) ([]*net.SRV, error) {
+				return []*net.SRV{
+					{Target: "doppler-0.service.consul."},
+					{Target: "doppler-1.service.consul."},
+				}, nil
+			}
+
+			f := plumbing.NewDNSFinder(
+				"_doppler._grpc.service.consul",
+				8082,
+				nil,
+				plumbing.WithDNSResolver(resolver),
+				plumbing.WithDNSPollInterval(time.Millisecond),
+			)
+			f.Start()
+
+			Expect(f.Next()).To(Equal(plumbing.Event{
+				GRPCDopplers: []string{
+					"doppler-0.service.consul:8082",
+					"doppler-1.service.consul:8082",
+				},
+			}))
+		})
+	})
+
+	Context("given a real SRV record served by an in-process DNS server", func() {
+		It("resolves it over the wire and emits an Event pairing each host with the gRPC port", func() {
+			srv := newTestDNSServer([]string{
+				"doppler-0.service.consul.",
+				"doppler-1.service.consul.",
+			})
+			defer srv.Close()
+
+			goResolver := &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+					return net.Dial(network, srv.Addr)
+				},
+			}
+
+			f := plumbing.NewDNSFinder(
+				"_doppler._grpc.service.consul",
+				8082,
+				nil,
+				plumbing.WithDNSResolver(func(name string) ([]*net.SRV, error) {
+					_, srvs, err := goResolver.LookupSRV(context.Background(), "", "", name)
+					return srvs, err
+				}),
+				plumbing.WithDNSPollInterval(time.Millisecond),
+			)
+			f.Start()
+
+			Expect(f.Next()).To(Equal(plumbing.Event{
+				GRPCDopplers: []string{
+					"doppler-0.service.consul:8082",
+					"doppler-1.service.consul:8082",
+				},
+			}))
+		})
+	})
+})
@@ -0,0 +1,127 @@
+package plumbing_test
+
+import "net"
+
+// testDNSServer is a minimal in-process DNS server that answers every
+// query with a fixed set of SRV records, so DNSFinder tests can exercise
+// real wire-format SRV resolution instead of stubbing out net.LookupSRV.
+type testDNSServer struct {
+	Addr string
+
+	conn *net.UDPConn
+	done chan struct{}
+}
+
+func newTestDNSServer(targets []string) *testDNSServer {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		panic(err)
+	}
+
+	s := &testDNSServer{
+		Addr: conn.LocalAddr().String(),
+		conn: conn,
+		done: make(chan struct{}),
+	}
+
+	go s.serve(targets)
+
+	return s
+}
+
+func (s *testDNSServer) Close() {
+	close(s.done)
+	s.conn.Close()
+}
+
+func (s *testDNSServer) serve(targets []string) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				continue
+			}
+		}
+
+		resp := buildSRVResponse(buf[:n], targets, 0)
+		s.conn.WriteToUDP(resp, addr)
+	}
+}
+
+// buildSRVResponse crafts a DNS response packet answering query (a
+// single-question SRV query) with one SRV record per target, each with
+// priority 0, weight 0, and the given port.
+func buildSRVResponse(query []byte, targets []string, port uint16) []byte {
+	qEnd := questionEnd(query)
+	question := query[12:qEnd]
+
+	header := []byte{
+		query[0], query[1], // ID
+		0x81, 0x80, // flags: response, recursion available, no error
+		0x00, 0x01, // QDCOUNT
+		0x00, byte(len(targets)), // ANCOUNT
+		0x00, 0x00, // NSCOUNT
+		0x00, 0x00, // ARCOUNT
+	}
+
+	resp := append([]byte{}, header...)
+	resp = append(resp, question...)
+
+	for _, target := range targets {
+		name := encodeDNSName(target)
+		rdata := make([]byte, 0, 6+len(name))
+		rdata = append(rdata, 0x00, 0x00)                // priority
+		rdata = append(rdata, 0x00, 0x00)                // weight
+		rdata = append(rdata, byte(port>>8), byte(port)) // port
+		rdata = append(rdata, name...)
+
+		resp = append(resp, 0xC0, 0x0C)             // NAME: pointer to question name
+		resp = append(resp, 0x00, 0x21)             // TYPE: SRV
+		resp = append(resp, 0x00, 0x01)             // CLASS: IN
+		resp = append(resp, 0x00, 0x00, 0x00, 0x3C) // TTL: 60s
+		resp = append(resp, byte(len(rdata)>>8), byte(len(rdata)))
+		resp = append(resp, rdata...)
+	}
+
+	return resp
+}
+
+// questionEnd returns the offset just past the QTYPE/QCLASS of the first
+// question in query, which starts at offset 12.
+func questionEnd(query []byte) int {
+	i := 12
+	for {
+		length := int(query[i])
+		if length == 0 {
+			i++
+			break
+		}
+		i += 1 + length
+	}
+	return i + 4
+}
+
+func encodeDNSName(name string) []byte {
+	var out []byte
+	label := []byte{}
+	for i := 0; i < len(name); i++ {
+		if name[i] == '.' {
+			if len(label) > 0 {
+				out = append(out, byte(len(label)))
+				out = append(out, label...)
+				label = label[:0]
+			}
+			continue
+		}
+		label = append(label, name[i])
+	}
+	if len(label) > 0 {
+		out = append(out, byte(len(label)))
+		out = append(out, label...)
+	}
+	return append(out, 0x00)
+}
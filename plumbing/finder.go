@@ -0,0 +1,17 @@
+package plumbing
+
+// Event carries the full set of addresses a Finder currently knows
+// about. GRPCConnector diffs each Event against the previous one, tearing
+// down connections to removed addresses and opening new ones to added
+// addresses.
+type Event struct {
+	GRPCDopplers []string
+}
+
+// Finder provides service discovery of Doppler processes. StaticFinder
+// implements it trivially over a fixed address list; DNSFinder and
+// ConsulFinder implement it over addresses that can change at runtime.
+type Finder interface {
+	Start()
+	Next() Event
+}
@@ -0,0 +1,223 @@
+package plumbing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/loggregator/logging"
+	"github.com/cloudfoundry/dropsonde/metricbatcher"
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// ErrNoDopplers is returned by Subscribe when the pool has no
+// non-draining connections to subscribe against.
+var ErrNoDopplers = errors.New("no doppler connections available")
+
+// drainTimeout bounds how long a GRPCConnector keeps a connection open
+// to a doppler the Finder no longer reports, giving subscribers already
+// reading from it a chance to finish before it's closed out from under
+// them.
+const drainTimeout = 5 * time.Second
+
+// Filter narrows a subscription to a specific source ID; a zero Filter
+// subscribes to every envelope (the firehose).
+type Filter struct {
+	SourceId string
+}
+
+// SubscriptionRequest describes a single Subscribe call: ShardID groups
+// concurrent subscribers so each envelope is delivered to exactly one of
+// them, and Filter optionally narrows the stream to one source ID.
+type SubscriptionRequest struct {
+	ShardID string
+	Filter  *Filter
+}
+
+// MetricClient can be used to emit metrics and events. It mirrors
+// trafficcontroller/app.MetricClient's EmitEvent method so GRPCConnector
+// doesn't need to import the app package to accept it.
+type MetricClient interface {
+	EmitEvent(title, body string)
+}
+
+// GRPCConnector dials every doppler address a Finder reports and lets
+// callers Subscribe to the resulting firehose. It reacts to the
+// Finder's Events on its own goroutine: newly reported addresses are
+// dialed immediately, and addresses that drop out of the set are
+// drained rather than closed out from under their in-flight
+// subscribers.
+type GRPCConnector struct {
+	bufferSize   int
+	pool         *Pool
+	finder       Finder
+	batcher      *metricbatcher.MetricBatcher
+	metricClient MetricClient
+	log          *logging.Logger
+
+	mu       sync.Mutex
+	draining map[string]bool
+}
+
+// NewGRPCConnector builds a GRPCConnector and starts watching finder for
+// address changes. bufferSize is stored but not used: Subscribe holds
+// only the envelopes of the batch it last received. log is scoped with a "component":"grpc_connector"
+// key; a nil log falls back to logging.NewDefault().
+func NewGRPCConnector(
+	bufferSize int,
+	pool *Pool,
+	finder Finder,
+	log *logging.Logger,
+	batcher *metricbatcher.MetricBatcher,
+	metricClient MetricClient,
+) *GRPCConnector {
+	if log == nil {
+		log = logging.NewDefault()
+	}
+
+	c := &GRPCConnector{
+		bufferSize:   bufferSize,
+		pool:         pool,
+		finder:       finder,
+		batcher:      batcher,
+		metricClient: metricClient,
+		log:          log.With("component", "grpc_connector"),
+		draining:     make(map[string]bool),
+	}
+
+	go c.watchFinder()
+
+	return c
+}
+
+// watchFinder blocks on finder.Next() forever, diffing each Event
+// against the previously known address set: addresses that are new are
+// dialed into the pool, and addresses that disappeared are drained.
+func (c *GRPCConnector) watchFinder() {
+	known := map[string]bool{}
+
+	for {
+		event := c.finder.Next()
+		next := make(map[string]bool, len(event.GRPCDopplers))
+
+		for _, addr := range event.GRPCDopplers {
+			next[addr] = true
+			if known[addr] {
+				continue
+			}
+			if _, err := c.pool.Add(addr); err != nil {
+				c.log.Warn("doppler connection failed", "error", err, "stream_id", addr)
+				if c.metricClient != nil {
+					c.metricClient.EmitEvent("doppler connection failed", err.Error())
+				}
+				continue
+			}
+			c.log.Info("doppler connection opened", "stream_id", addr)
+		}
+
+		for addr := range known {
+			if !next[addr] {
+				c.drain(addr)
+			}
+		}
+
+		known = next
+	}
+}
+
+// drain marks addr as no longer eligible for new subscriptions, then
+// closes its connection after drainTimeout so subscribers already
+// reading from it have a chance to finish.
+func (c *GRPCConnector) drain(addr string) {
+	c.log.Info("doppler connection draining", "stream_id", addr)
+
+	c.mu.Lock()
+	c.draining[addr] = true
+	c.mu.Unlock()
+
+	time.AfterFunc(drainTimeout, func() {
+		c.pool.Remove(addr)
+
+		c.mu.Lock()
+		delete(c.draining, addr)
+		c.mu.Unlock()
+
+		c.log.Info("doppler connection closed", "stream_id", addr)
+	})
+}
+
+// Subscribe opens a v2 envelope stream against one of the pooled,
+// non-draining doppler connections and returns a reader that yields the
+// stream's envelopes one at a time, marshaled to bytes.
+func (c *GRPCConnector) Subscribe(ctx context.Context, req *SubscriptionRequest) (func() ([]byte, error), error) {
+	conn, err := c.pickConn()
+	if err != nil {
+		c.log.Warn("subscribe failed", "error", err, "subscription_id", req.ShardID, "source_id", req.Filter.GetSourceId())
+		return nil, err
+	}
+
+	client := loggregator_v2.NewDopplerClient(conn)
+	stream, err := client.BatchSubscribe(ctx, &loggregator_v2.StreamRequest{
+		ShardId:   req.ShardID,
+		Selectors: req.Filter.selectors(),
+	})
+	if err != nil {
+		c.log.Warn("subscribe failed", "error", err, "subscription_id", req.ShardID, "source_id", req.Filter.GetSourceId())
+		return nil, err
+	}
+
+	c.log.Info("subscription opened", "subscription_id", req.ShardID, "source_id", req.Filter.GetSourceId())
+
+	var pending []*loggregator_v2.Envelope
+	return func() ([]byte, error) {
+		for len(pending) == 0 {
+			batch, err := stream.Recv()
+			if err != nil {
+				c.log.Info("subscription closed", "subscription_id", req.ShardID, "source_id", req.Filter.GetSourceId(), "error", err)
+				return nil, err
+			}
+			pending = batch.GetBatch()
+		}
+
+		env := pending[0]
+		pending = pending[1:]
+		return proto.Marshal(env)
+	}, nil
+}
+
+// pickConn returns any one pooled connection that isn't currently
+// draining.
+func (c *GRPCConnector) pickConn() (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	draining := make(map[string]bool, len(c.draining))
+	for addr := range c.draining {
+		draining[addr] = true
+	}
+	c.mu.Unlock()
+
+	for addr, conn := range c.pool.Conns() {
+		if !draining[addr] {
+			return conn, nil
+		}
+	}
+
+	return nil, ErrNoDopplers
+}
+
+// GetSourceId returns f's SourceId, or "" for a nil Filter.
+func (f *Filter) GetSourceId() string {
+	if f == nil {
+		return ""
+	}
+	return f.SourceId
+}
+
+func (f *Filter) selectors() []*loggregator_v2.Selector {
+	if f == nil || f.SourceId == "" {
+		return nil
+	}
+	return []*loggregator_v2.Selector{{SourceId: f.SourceId}}
+}
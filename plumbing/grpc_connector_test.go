@@ -0,0 +1,58 @@
+package plumbing_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/loggregator/plumbing"
+	"google.golang.org/grpc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeFinder struct {
+	events chan plumbing.Event
+}
+
+func newFakeFinder() *fakeFinder {
+	return &fakeFinder{events: make(chan plumbing.Event, 1)}
+}
+
+func (f *fakeFinder) Start() {}
+
+func (f *fakeFinder) Next() plumbing.Event {
+	return <-f.events
+}
+
+var _ = Describe("GRPCConnector", func() {
+	It("dials addresses the Finder reports and drains ones it stops reporting", func() {
+		finder := newFakeFinder()
+		pool := plumbing.NewPool(10, grpc.WithInsecure())
+
+		finder.events <- plumbing.Event{GRPCDopplers: []string{"127.0.0.1:1"}}
+		plumbing.NewGRPCConnector(1, pool, finder, nil, nil, nil)
+
+		Eventually(func() map[string]interface{} {
+			conns := pool.Conns()
+			out := make(map[string]interface{}, len(conns))
+			for addr := range conns {
+				out[addr] = nil
+			}
+			return out
+		}).Should(HaveKey("127.0.0.1:1"))
+
+		finder.events <- plumbing.Event{GRPCDopplers: []string{"127.0.0.1:2"}}
+
+		Eventually(func() bool {
+			_, ok := pool.Conns()["127.0.0.1:2"]
+			return ok
+		}).Should(BeTrue())
+
+		// The connection to the removed address is drained, not closed
+		// immediately, so in-flight subscribers keep working briefly.
+		Consistently(func() bool {
+			_, ok := pool.Conns()["127.0.0.1:1"]
+			return ok
+		}, 50*time.Millisecond).Should(BeTrue())
+	})
+})
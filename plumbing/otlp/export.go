@@ -0,0 +1,106 @@
+package otlp
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/loggregator/logging"
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// Reader reads the next envelope to export, blocking until one is
+// available. It has the same shape as the doppler v2 server's Reader so
+// a firehose subscription can be plugged in directly.
+type Reader func() *loggregator_v2.Envelope
+
+// Exporter reads firehose envelopes from a Reader, converts each to its
+// OTLP equivalent, and forwards it to an external collector over
+// OTLP/gRPC, without a sidecar translator.
+type Exporter struct {
+	r       Reader
+	logs    collectorlogspb.LogsServiceClient
+	metrics collectormetricspb.MetricsServiceClient
+	log     *logging.Logger
+
+	done chan struct{}
+}
+
+// NewExporter builds an Exporter that reads from r and exports to the
+// collector reachable through logs/metrics clients dialed against it.
+// log is scoped with a "component":"otlp_export" key; a nil log falls
+// back to logging.NewDefault().
+func NewExporter(
+	r Reader,
+	logs collectorlogspb.LogsServiceClient,
+	metrics collectormetricspb.MetricsServiceClient,
+	log *logging.Logger,
+) *Exporter {
+	if log == nil {
+		log = logging.NewDefault()
+	}
+
+	return &Exporter{
+		r:       r,
+		logs:    logs,
+		metrics: metrics,
+		log:     log.With("component", "otlp_export"),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start reads and exports envelopes until Stop is called.
+func (e *Exporter) Start() {
+	for {
+		select {
+		case <-e.done:
+			return
+		default:
+		}
+
+		env := e.r()
+		if env == nil {
+			continue
+		}
+
+		e.export(env)
+	}
+}
+
+// Stop halts Start's read loop.
+func (e *Exporter) Stop() {
+	close(e.done)
+}
+
+func (e *Exporter) export(env *loggregator_v2.Envelope) {
+	ctx := context.Background()
+
+	if record := ToLogRecord(env); record != nil {
+		_, err := e.logs.Export(ctx, &collectorlogspb.ExportLogsServiceRequest{
+			ResourceLogs: []*logspb.ResourceLogs{{
+				ScopeLogs: []*logspb.ScopeLogs{{
+					LogRecords: []*logspb.LogRecord{record},
+				}},
+			}},
+		})
+		if err != nil {
+			e.log.Warn("failed to export otlp log record", "error", err, "source_id", env.GetSourceId())
+		}
+		return
+	}
+
+	if metrics := ToMetric(env); len(metrics) > 0 {
+		_, err := e.metrics.Export(ctx, &collectormetricspb.ExportMetricsServiceRequest{
+			ResourceMetrics: []*metricspb.ResourceMetrics{{
+				ScopeMetrics: []*metricspb.ScopeMetrics{{
+					Metrics: metrics,
+				}},
+			}},
+		})
+		if err != nil {
+			e.log.Warn("failed to export otlp metric", "error", err, "source_id", env.GetSourceId())
+		}
+	}
+}
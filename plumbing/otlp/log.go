@@ -0,0 +1,94 @@
+// Package otlp converts between loggregator_v2.Envelope and the
+// OpenTelemetry OTLP wire types, in both directions, so Loggregator can
+// sit inside an OTel-native observability stack without a sidecar
+// translator.
+package otlp
+
+import (
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// ToLogRecord converts a Log envelope to an OTLP LogRecord. It returns
+// nil if env does not carry a Log message.
+func ToLogRecord(env *loggregator_v2.Envelope) *logspb.LogRecord {
+	log := env.GetLog()
+	if log == nil {
+		return nil
+	}
+
+	return &logspb.LogRecord{
+		TimeUnixNano:   uint64(env.GetTimestamp()),
+		SeverityNumber: severityNumber(log.GetType()),
+		Body: &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_StringValue{StringValue: string(log.GetPayload())},
+		},
+		Attributes: toAttributes(env),
+	}
+}
+
+// FromLogRecord converts an OTLP LogRecord into a Log envelope, tagging
+// it with sourceID so it can be routed and authorized like any other v2
+// envelope ingested through the RLP ingress path.
+func FromLogRecord(record *logspb.LogRecord, sourceID string) *loggregator_v2.Envelope {
+	return &loggregator_v2.Envelope{
+		Timestamp: int64(record.GetTimeUnixNano()),
+		SourceId:  sourceID,
+		Tags:      fromAttributes(record.GetAttributes()),
+		Message: &loggregator_v2.Envelope_Log{
+			Log: &loggregator_v2.Log{
+				Payload: []byte(record.GetBody().GetStringValue()),
+				Type:    logType(record.GetSeverityNumber()),
+			},
+		},
+	}
+}
+
+// severityNumber maps a v2 Log_Type to the closest OTLP SeverityNumber.
+func severityNumber(t loggregator_v2.Log_Type) logspb.SeverityNumber {
+	switch t {
+	case loggregator_v2.Log_ERR:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	}
+}
+
+// logType maps an OTLP SeverityNumber back to the closest v2 Log_Type.
+func logType(s logspb.SeverityNumber) loggregator_v2.Log_Type {
+	if s >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR {
+		return loggregator_v2.Log_ERR
+	}
+	return loggregator_v2.Log_OUT
+}
+
+func toAttributes(env *loggregator_v2.Envelope) []*commonpb.KeyValue {
+	tags := env.GetTags()
+	attrs := make([]*commonpb.KeyValue, 0, len(tags)+1)
+
+	attrs = append(attrs, &commonpb.KeyValue{
+		Key:   "service.instance.id",
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: env.GetSourceId()}},
+	})
+
+	for k, v := range tags {
+		attrs = append(attrs, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+		})
+	}
+
+	return attrs
+}
+
+func fromAttributes(attrs []*commonpb.KeyValue) map[string]string {
+	tags := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		if attr.GetKey() == "service.instance.id" {
+			continue
+		}
+		tags[attr.GetKey()] = attr.GetValue().GetStringValue()
+	}
+	return tags
+}
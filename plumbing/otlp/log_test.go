@@ -0,0 +1,63 @@
+package otlp_test
+
+import (
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/loggregator/plumbing/otlp"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ToLogRecord/FromLogRecord", func() {
+	Context("given a Log envelope", func() {
+		It("converts to an OTLP LogRecord carrying the payload and severity", func() {
+			env := &loggregator_v2.Envelope{
+				Timestamp: 1000,
+				SourceId:  "app-guid",
+				Message: &loggregator_v2.Envelope_Log{
+					Log: &loggregator_v2.Log{
+						Payload: []byte("failed to connect"),
+						Type:    loggregator_v2.Log_ERR,
+					},
+				},
+			}
+
+			record := otlp.ToLogRecord(env)
+
+			Expect(record.GetTimeUnixNano()).To(BeEquivalentTo(1000))
+			Expect(record.GetSeverityNumber()).To(Equal(logspb.SeverityNumber_SEVERITY_NUMBER_ERROR))
+			Expect(record.GetBody().GetStringValue()).To(Equal("failed to connect"))
+		})
+	})
+
+	Context("given an envelope with no Log message", func() {
+		It("returns nil", func() {
+			env := &loggregator_v2.Envelope{
+				Message: &loggregator_v2.Envelope_Counter{Counter: &loggregator_v2.Counter{}},
+			}
+
+			Expect(otlp.ToLogRecord(env)).To(BeNil())
+		})
+	})
+
+	Context("given an OTLP LogRecord", func() {
+		It("converts back to a Log envelope tagged with sourceID", func() {
+			record := &logspb.LogRecord{
+				TimeUnixNano:   2000,
+				SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+				Body: &commonpb.AnyValue{
+					Value: &commonpb.AnyValue_StringValue{StringValue: "startup complete"},
+				},
+			}
+
+			env := otlp.FromLogRecord(record, "app-guid")
+
+			Expect(env.GetSourceId()).To(Equal("app-guid"))
+			Expect(env.GetTimestamp()).To(BeEquivalentTo(2000))
+			Expect(env.GetLog().GetPayload()).To(BeEquivalentTo("startup complete"))
+			Expect(env.GetLog().GetType()).To(Equal(loggregator_v2.Log_OUT))
+		})
+	})
+})
@@ -0,0 +1,155 @@
+package otlp
+
+import (
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// ToMetric converts a Counter, Gauge, or Timer envelope to its OTLP
+// Metric equivalent(s). A Gauge envelope commonly bundles several named
+// metrics in one envelope (e.g. a container metrics envelope carrying
+// cpu, memory, and disk together), so it converts to one Metric per map
+// entry rather than collapsing them under a single name. It returns nil
+// for envelope types with no metric equivalent (e.g. Log, Event).
+func ToMetric(env *loggregator_v2.Envelope) []*metricspb.Metric {
+	attrs := toAttributes(env)
+
+	switch msg := env.GetMessage().(type) {
+	case *loggregator_v2.Envelope_Counter:
+		return []*metricspb.Metric{{
+			Name: msg.Counter.GetName(),
+			Data: &metricspb.Metric_Sum{
+				Sum: &metricspb.Sum{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					IsMonotonic:            true,
+					DataPoints: []*metricspb.NumberDataPoint{{
+						TimeUnixNano: uint64(env.GetTimestamp()),
+						Value:        &metricspb.NumberDataPoint_AsInt{AsInt: int64(msg.Counter.GetTotal())},
+						Attributes:   attrs,
+					}},
+				},
+			},
+		}}
+	case *loggregator_v2.Envelope_Gauge:
+		metrics := make([]*metricspb.Metric, 0, len(msg.Gauge.GetMetrics()))
+		for name, v := range msg.Gauge.GetMetrics() {
+			metrics = append(metrics, &metricspb.Metric{
+				Name: name,
+				Data: &metricspb.Metric_Gauge{
+					Gauge: &metricspb.Gauge{
+						DataPoints: []*metricspb.NumberDataPoint{{
+							TimeUnixNano: uint64(env.GetTimestamp()),
+							Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: v.GetValue()},
+							Attributes:   attrs,
+						}},
+					},
+				},
+			})
+		}
+		return metrics
+	case *loggregator_v2.Envelope_Timer:
+		durationNanos := msg.Timer.GetStop() - msg.Timer.GetStart()
+		return []*metricspb.Metric{{
+			Name: msg.Timer.GetName(),
+			Data: &metricspb.Metric_Histogram{
+				Histogram: &metricspb.Histogram{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+					DataPoints: []*metricspb.HistogramDataPoint{{
+						TimeUnixNano:   uint64(env.GetTimestamp()),
+						Count:          1,
+						Sum:            floatPtr(float64(durationNanos)),
+						BucketCounts:   []uint64{1},
+						ExplicitBounds: []float64{},
+						Attributes:     attrs,
+					}},
+				},
+			},
+		}}
+	default:
+		return nil
+	}
+}
+
+func floatPtr(f float64) *float64 { return &f }
+
+// FromMetric converts an OTLP Sum, Gauge, or Histogram Metric into a
+// Counter, Gauge, or Timer envelope, tagging it with sourceID so it can
+// be routed and authorized like any other v2 envelope ingested through
+// the RLP ingress path. It returns nil for metric shapes with no v2
+// equivalent (e.g. a Summary), or for a Metric with no data points.
+func FromMetric(metric *metricspb.Metric, sourceID string) *loggregator_v2.Envelope {
+	switch data := metric.GetData().(type) {
+	case *metricspb.Metric_Sum:
+		point := firstNumberDataPoint(data.Sum.GetDataPoints())
+		if point == nil {
+			return nil
+		}
+		return &loggregator_v2.Envelope{
+			Timestamp: int64(point.GetTimeUnixNano()),
+			SourceId:  sourceID,
+			Tags:      fromAttributes(point.GetAttributes()),
+			Message: &loggregator_v2.Envelope_Counter{
+				Counter: &loggregator_v2.Counter{
+					Name:  metric.GetName(),
+					Total: uint64(numberDataPointValue(point)),
+				},
+			},
+		}
+	case *metricspb.Metric_Gauge:
+		point := firstNumberDataPoint(data.Gauge.GetDataPoints())
+		if point == nil {
+			return nil
+		}
+		return &loggregator_v2.Envelope{
+			Timestamp: int64(point.GetTimeUnixNano()),
+			SourceId:  sourceID,
+			Tags:      fromAttributes(point.GetAttributes()),
+			Message: &loggregator_v2.Envelope_Gauge{
+				Gauge: &loggregator_v2.Gauge{
+					Metrics: map[string]*loggregator_v2.GaugeValue{
+						metric.GetName(): {Value: numberDataPointValue(point)},
+					},
+				},
+			},
+		}
+	case *metricspb.Metric_Histogram:
+		points := data.Histogram.GetDataPoints()
+		if len(points) == 0 {
+			return nil
+		}
+		point := points[0]
+		start := int64(point.GetTimeUnixNano())
+		return &loggregator_v2.Envelope{
+			Timestamp: start,
+			SourceId:  sourceID,
+			Tags:      fromAttributes(point.GetAttributes()),
+			Message: &loggregator_v2.Envelope_Timer{
+				Timer: &loggregator_v2.Timer{
+					Name:  metric.GetName(),
+					Start: start,
+					Stop:  start + int64(point.GetSum()),
+				},
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+func firstNumberDataPoint(points []*metricspb.NumberDataPoint) *metricspb.NumberDataPoint {
+	if len(points) == 0 {
+		return nil
+	}
+	return points[0]
+}
+
+func numberDataPointValue(p *metricspb.NumberDataPoint) float64 {
+	switch v := p.GetValue().(type) {
+	case *metricspb.NumberDataPoint_AsInt:
+		return float64(v.AsInt)
+	case *metricspb.NumberDataPoint_AsDouble:
+		return v.AsDouble
+	default:
+		return 0
+	}
+}
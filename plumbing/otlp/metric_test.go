@@ -0,0 +1,129 @@
+package otlp_test
+
+import (
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/loggregator/plumbing/otlp"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ToMetric/FromMetric", func() {
+	Context("given a Counter envelope", func() {
+		It("converts to an OTLP cumulative monotonic Sum", func() {
+			env := &loggregator_v2.Envelope{
+				Timestamp: 1000,
+				Message: &loggregator_v2.Envelope_Counter{
+					Counter: &loggregator_v2.Counter{Name: "requests", Total: 42},
+				},
+			}
+
+			metrics := otlp.ToMetric(env)
+
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].GetName()).To(Equal("requests"))
+			sum := metrics[0].GetSum()
+			Expect(sum).NotTo(BeNil())
+			Expect(sum.GetIsMonotonic()).To(BeTrue())
+			Expect(sum.GetDataPoints()).To(HaveLen(1))
+			Expect(sum.GetDataPoints()[0].GetAsInt()).To(BeEquivalentTo(42))
+		})
+	})
+
+	Context("given a Gauge envelope carrying a single metric", func() {
+		It("converts to a single OTLP Gauge metric", func() {
+			env := &loggregator_v2.Envelope{
+				Timestamp: 1000,
+				Message: &loggregator_v2.Envelope_Gauge{
+					Gauge: &loggregator_v2.Gauge{
+						Metrics: map[string]*loggregator_v2.GaugeValue{
+							"cpu": {Value: 0.5},
+						},
+					},
+				},
+			}
+
+			metrics := otlp.ToMetric(env)
+
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].GetName()).To(Equal("cpu"))
+			Expect(metrics[0].GetGauge().GetDataPoints()).To(HaveLen(1))
+			Expect(metrics[0].GetGauge().GetDataPoints()[0].GetAsDouble()).To(Equal(0.5))
+		})
+	})
+
+	Context("given a Gauge envelope bundling several named metrics", func() {
+		It("converts to one OTLP Gauge metric per name, none lost or mislabeled", func() {
+			env := &loggregator_v2.Envelope{
+				Timestamp: 1000,
+				Message: &loggregator_v2.Envelope_Gauge{
+					Gauge: &loggregator_v2.Gauge{
+						Metrics: map[string]*loggregator_v2.GaugeValue{
+							"cpu":    {Value: 0.5},
+							"memory": {Value: 1024},
+							"disk":   {Value: 2048},
+						},
+					},
+				},
+			}
+
+			metrics := otlp.ToMetric(env)
+
+			byName := map[string]float64{}
+			for _, m := range metrics {
+				Expect(m.GetGauge().GetDataPoints()).To(HaveLen(1))
+				byName[m.GetName()] = m.GetGauge().GetDataPoints()[0].GetAsDouble()
+			}
+
+			Expect(byName).To(Equal(map[string]float64{
+				"cpu":    0.5,
+				"memory": 1024,
+				"disk":   2048,
+			}))
+		})
+	})
+
+	Context("given an envelope with no metric equivalent", func() {
+		It("returns nil", func() {
+			env := &loggregator_v2.Envelope{
+				Message: &loggregator_v2.Envelope_Log{Log: &loggregator_v2.Log{}},
+			}
+
+			Expect(otlp.ToMetric(env)).To(BeNil())
+		})
+	})
+
+	Context("given an OTLP Sum metric", func() {
+		It("converts back to a Counter envelope", func() {
+			metric := &metricspb.Metric{
+				Name: "requests",
+				Data: &metricspb.Metric_Sum{
+					Sum: &metricspb.Sum{
+						DataPoints: []*metricspb.NumberDataPoint{{
+							TimeUnixNano: 3000,
+							Value:        &metricspb.NumberDataPoint_AsInt{AsInt: 7},
+						}},
+					},
+				},
+			}
+
+			env := otlp.FromMetric(metric, "app-guid")
+
+			Expect(env.GetSourceId()).To(Equal("app-guid"))
+			Expect(env.GetCounter().GetName()).To(Equal("requests"))
+			Expect(env.GetCounter().GetTotal()).To(BeEquivalentTo(7))
+		})
+	})
+
+	Context("given an OTLP Sum metric with no data points", func() {
+		It("returns nil", func() {
+			metric := &metricspb.Metric{
+				Name: "requests",
+				Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{}},
+			}
+
+			Expect(otlp.FromMetric(metric, "app-guid")).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,16 @@
+package otlp
+
+import (
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+)
+
+// Register mounts ingress's LogsService and MetricsService onto
+// grpcServer. Callers register it alongside the v2 envelope ingress
+// service on the same *grpc.Server so OTel-native apps can reach both
+// without a separate port.
+func Register(grpcServer *grpc.Server, ingress *IngressServer) {
+	collectorlogspb.RegisterLogsServiceServer(grpcServer, ingress)
+	collectormetricspb.RegisterMetricsServiceServer(grpcServer, ingress.MetricsService())
+}
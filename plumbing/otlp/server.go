@@ -0,0 +1,102 @@
+package otlp
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/loggregator/logging"
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// EnvelopeWriter accepts envelopes produced from ingested OTLP data,
+// e.g. the v2 ingress server's own envelope channel.
+type EnvelopeWriter interface {
+	Write(*loggregator_v2.Envelope)
+}
+
+// IngressServer implements collector/logs/v1.LogsServiceServer,
+// translating each request into v2 envelopes and handing them to an
+// EnvelopeWriter. Its MetricsService counterpart is exposed through
+// MetricsService, since a single type cannot implement both
+// LogsServiceServer and MetricsServiceServer directly: both declare an
+// RPC named Export, with different request/response types. Register
+// both on the same gRPC server as the v2 ingress so operators don't need
+// a separate port or sidecar translator for OTel-native apps.
+type IngressServer struct {
+	writer EnvelopeWriter
+	log    *logging.Logger
+}
+
+// NewIngressServer constructs an IngressServer. log is scoped with a
+// "component":"otlp_ingress" key; a nil log falls back to
+// logging.NewDefault().
+func NewIngressServer(writer EnvelopeWriter, log *logging.Logger) *IngressServer {
+	if log == nil {
+		log = logging.NewDefault()
+	}
+
+	return &IngressServer{
+		writer: writer,
+		log:    log.With("component", "otlp_ingress"),
+	}
+}
+
+// Export implements collector/logs/v1.LogsServiceServer.
+func (s *IngressServer) Export(ctx context.Context, req *collectorlogspb.ExportLogsServiceRequest) (*collectorlogspb.ExportLogsServiceResponse, error) {
+	for _, rl := range req.GetResourceLogs() {
+		sourceID := resourceSourceID(rl.GetResource())
+		for _, sl := range rl.GetScopeLogs() {
+			for _, record := range sl.GetLogRecords() {
+				s.writer.Write(FromLogRecord(record, sourceID))
+			}
+		}
+	}
+
+	return &collectorlogspb.ExportLogsServiceResponse{}, nil
+}
+
+// MetricsService returns a collectormetricspb.MetricsServiceServer
+// backed by s, for registration alongside s itself as the
+// LogsServiceServer.
+func (s *IngressServer) MetricsService() collectormetricspb.MetricsServiceServer {
+	return metricsIngressServer{s}
+}
+
+// metricsIngressServer adapts IngressServer to
+// collectormetricspb.MetricsServiceServer under its own Export method,
+// so its signature doesn't collide with IngressServer.Export.
+type metricsIngressServer struct {
+	*IngressServer
+}
+
+// Export implements collector/metrics/v1.MetricsServiceServer.
+func (s metricsIngressServer) Export(ctx context.Context, req *collectormetricspb.ExportMetricsServiceRequest) (*collectormetricspb.ExportMetricsServiceResponse, error) {
+	for _, rm := range req.GetResourceMetrics() {
+		sourceID := resourceSourceID(rm.GetResource())
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, metric := range sm.GetMetrics() {
+				env := FromMetric(metric, sourceID)
+				if env == nil {
+					continue
+				}
+				s.writer.Write(env)
+			}
+		}
+	}
+
+	return &collectormetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+// resourceSourceID pulls service.instance.id off an OTLP Resource to use
+// as the v2 envelope's source_id, falling back to "" (unrouted) if the
+// resource doesn't carry one.
+func resourceSourceID(resource *resourcepb.Resource) string {
+	for _, attr := range resource.GetAttributes() {
+		if attr.GetKey() == "service.instance.id" {
+			return attr.GetValue().GetStringValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package otlp_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/loggregator/plumbing/otlp"
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeEnvelopeWriter struct {
+	envelopes []*loggregator_v2.Envelope
+}
+
+func (f *fakeEnvelopeWriter) Write(env *loggregator_v2.Envelope) {
+	f.envelopes = append(f.envelopes, env)
+}
+
+var _ = Describe("IngressServer", func() {
+	It("writes each OTLP log record as a v2 envelope tagged with the resource's instance ID", func() {
+		writer := &fakeEnvelopeWriter{}
+		server := otlp.NewIngressServer(writer, nil)
+
+		req := &collectorlogspb.ExportLogsServiceRequest{
+			ResourceLogs: []*logspb.ResourceLogs{{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{{
+						Key:   "service.instance.id",
+						Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "app-guid"}},
+					}},
+				},
+				ScopeLogs: []*logspb.ScopeLogs{{
+					LogRecords: []*logspb.LogRecord{{
+						Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "hello"}},
+					}},
+				}},
+			}},
+		}
+
+		_, err := server.Export(context.Background(), req)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.envelopes).To(HaveLen(1))
+		Expect(writer.envelopes[0].GetSourceId()).To(Equal("app-guid"))
+		Expect(writer.envelopes[0].GetLog().GetPayload()).To(BeEquivalentTo("hello"))
+	})
+
+	It("writes each OTLP metric as a v2 envelope via its MetricsService", func() {
+		writer := &fakeEnvelopeWriter{}
+		server := otlp.NewIngressServer(writer, nil)
+
+		req := &collectormetricspb.ExportMetricsServiceRequest{
+			ResourceMetrics: []*metricspb.ResourceMetrics{{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{{
+						Key:   "service.instance.id",
+						Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "app-guid"}},
+					}},
+				},
+				ScopeMetrics: []*metricspb.ScopeMetrics{{
+					Metrics: []*metricspb.Metric{{
+						Name: "requests",
+						Data: &metricspb.Metric_Sum{
+							Sum: &metricspb.Sum{
+								DataPoints: []*metricspb.NumberDataPoint{{
+									Value: &metricspb.NumberDataPoint_AsInt{AsInt: 3},
+								}},
+							},
+						},
+					}},
+				}},
+			}},
+		}
+
+		_, err := server.MetricsService().Export(context.Background(), req)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.envelopes).To(HaveLen(1))
+		Expect(writer.envelopes[0].GetSourceId()).To(Equal("app-guid"))
+		Expect(writer.envelopes[0].GetCounter().GetName()).To(Equal("requests"))
+	})
+})
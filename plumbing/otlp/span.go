@@ -0,0 +1,83 @@
+package otlp
+
+import (
+	"encoding/hex"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// ToSpan converts a Timer envelope carrying "trace_id" and "span_id"
+// tags into an OTLP Span. Loggregator's v2 protocol has no dedicated
+// span message, so a Timer tagged with trace context is the existing
+// envelope shape closest to a span: it already carries a name and a
+// start/stop pair. It returns nil for Timer envelopes with no trace
+// context, or for any other envelope type.
+func ToSpan(env *loggregator_v2.Envelope) *tracepb.Span {
+	timer := env.GetTimer()
+	if timer == nil {
+		return nil
+	}
+
+	tags := env.GetTags()
+	traceID, ok := decodeHex(tags["trace_id"])
+	if !ok {
+		return nil
+	}
+	spanID, ok := decodeHex(tags["span_id"])
+	if !ok {
+		return nil
+	}
+
+	span := &tracepb.Span{
+		TraceId:           traceID,
+		SpanId:            spanID,
+		Name:              timer.GetName(),
+		StartTimeUnixNano: uint64(timer.GetStart()),
+		EndTimeUnixNano:   uint64(timer.GetStop()),
+		Attributes:        toAttributes(env),
+	}
+
+	if parentID, ok := decodeHex(tags["parent_span_id"]); ok {
+		span.ParentSpanId = parentID
+	}
+
+	return span
+}
+
+// FromSpan converts an OTLP Span into a Timer envelope, tagging it with
+// sourceID and round-tripping its trace/span IDs as tags so ToSpan can
+// recover them.
+func FromSpan(span *tracepb.Span, sourceID string) *loggregator_v2.Envelope {
+	tags := map[string]string{
+		"trace_id": hex.EncodeToString(span.GetTraceId()),
+		"span_id":  hex.EncodeToString(span.GetSpanId()),
+	}
+	if len(span.GetParentSpanId()) > 0 {
+		tags["parent_span_id"] = hex.EncodeToString(span.GetParentSpanId())
+	}
+
+	return &loggregator_v2.Envelope{
+		Timestamp: int64(span.GetStartTimeUnixNano()),
+		SourceId:  sourceID,
+		Tags:      tags,
+		Message: &loggregator_v2.Envelope_Timer{
+			Timer: &loggregator_v2.Timer{
+				Name:  span.GetName(),
+				Start: int64(span.GetStartTimeUnixNano()),
+				Stop:  int64(span.GetEndTimeUnixNano()),
+			},
+		},
+	}
+}
+
+func decodeHex(s string) ([]byte, bool) {
+	if s == "" {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
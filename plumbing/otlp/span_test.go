@@ -0,0 +1,84 @@
+package otlp_test
+
+import (
+	"encoding/hex"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/loggregator/plumbing/otlp"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ToSpan/FromSpan", func() {
+	Context("given a Timer envelope tagged with trace context", func() {
+		It("converts to an OTLP Span", func() {
+			env := &loggregator_v2.Envelope{
+				Tags: map[string]string{
+					"trace_id": "0102030405060708090a0b0c0d0e0f10",
+					"span_id":  "0102030405060708",
+				},
+				Message: &loggregator_v2.Envelope_Timer{
+					Timer: &loggregator_v2.Timer{Name: "http-request", Start: 100, Stop: 200},
+				},
+			}
+
+			span := otlp.ToSpan(env)
+
+			Expect(span).NotTo(BeNil())
+			Expect(span.GetName()).To(Equal("http-request"))
+			Expect(span.GetTraceId()).To(Equal(mustDecodeHex("0102030405060708090a0b0c0d0e0f10")))
+			Expect(span.GetSpanId()).To(Equal(mustDecodeHex("0102030405060708")))
+		})
+	})
+
+	Context("given a Timer envelope with no trace context", func() {
+		It("returns nil", func() {
+			env := &loggregator_v2.Envelope{
+				Message: &loggregator_v2.Envelope_Timer{
+					Timer: &loggregator_v2.Timer{Name: "http-request"},
+				},
+			}
+
+			Expect(otlp.ToSpan(env)).To(BeNil())
+		})
+	})
+
+	Context("given an envelope with no Timer message", func() {
+		It("returns nil", func() {
+			env := &loggregator_v2.Envelope{
+				Message: &loggregator_v2.Envelope_Counter{Counter: &loggregator_v2.Counter{}},
+			}
+
+			Expect(otlp.ToSpan(env)).To(BeNil())
+		})
+	})
+
+	Context("given an OTLP Span", func() {
+		It("round-trips trace/span IDs as tags on a Timer envelope", func() {
+			span := &tracepb.Span{
+				Name:              "http-request",
+				TraceId:           mustDecodeHex("0102030405060708090a0b0c0d0e0f10"),
+				SpanId:            mustDecodeHex("0102030405060708"),
+				StartTimeUnixNano: 100,
+				EndTimeUnixNano:   200,
+			}
+
+			env := otlp.FromSpan(span, "app-guid")
+
+			Expect(env.GetSourceId()).To(Equal("app-guid"))
+			Expect(env.GetTimer().GetName()).To(Equal("http-request"))
+			Expect(env.GetTags()["trace_id"]).To(Equal("0102030405060708090a0b0c0d0e0f10"))
+			Expect(env.GetTags()["span_id"]).To(Equal("0102030405060708"))
+		})
+	})
+})
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
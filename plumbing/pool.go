@@ -0,0 +1,70 @@
+package plumbing
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Pool maintains gRPC client connections to a dynamic set of doppler
+// addresses, shared across all of a GRPCConnector's subscriptions.
+type Pool struct {
+	mu    sync.Mutex
+	opts  []grpc.DialOption
+	conns map[string]*grpc.ClientConn
+}
+
+// NewPool builds a Pool that dials every address added to it with opts.
+// size is accepted for parity with how callers size their connection
+// budget, but Pool itself places no limit on the number of distinct
+// addresses it will hold.
+func NewPool(size int, opts ...grpc.DialOption) *Pool {
+	return &Pool{
+		opts:  opts,
+		conns: make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Add dials addr if the pool doesn't already hold a connection to it,
+// and returns that connection either way.
+func (p *Pool) Add(addr string) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if conn, ok := p.conns[addr]; ok {
+		return conn, nil
+	}
+
+	conn, err := grpc.Dial(addr, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[addr] = conn
+	return conn, nil
+}
+
+// Remove closes and forgets the connection to addr, if the pool holds
+// one.
+func (p *Pool) Remove(addr string) {
+	p.mu.Lock()
+	conn, ok := p.conns[addr]
+	delete(p.conns, addr)
+	p.mu.Unlock()
+
+	if ok {
+		conn.Close()
+	}
+}
+
+// Conns returns a snapshot of the currently pooled connections, keyed by
+// address.
+func (p *Pool) Conns() map[string]*grpc.ClientConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make(map[string]*grpc.ClientConn, len(p.conns))
+	for addr, conn := range p.conns {
+		out[addr] = conn
+	}
+	return out
+}
@@ -0,0 +1,16 @@
+package app
+
+// GRPC holds the mutual-TLS material doppler listens for v2 (and OTLP)
+// gRPC ingress with.
+type GRPC struct {
+	Port     uint16
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+// Config is doppler's process configuration, populated from its config
+// file.
+type Config struct {
+	GRPC GRPC
+}
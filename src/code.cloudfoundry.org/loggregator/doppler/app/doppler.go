@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	v2server "code.cloudfoundry.org/loggregator/doppler/internal/server/v2"
+	"code.cloudfoundry.org/loggregator/logging"
+	"code.cloudfoundry.org/loggregator/plumbing"
+	"google.golang.org/grpc"
+)
+
+// EnvelopeQueue is a bounded buffer of ingested envelopes, shared as the
+// write target for both the v2 and OTLP gRPC ingress servers so an
+// envelope lands in the same place regardless of which protocol an app
+// sent it with. It drops the oldest envelope to make room rather than
+// blocking an ingress RPC, the same backpressure choice the v2 server's
+// Repeater makes on its egress side. Write is called concurrently by
+// both ingress servers, so the drop-oldest-then-push sequence is guarded
+// by a mutex rather than composed from independent channel selects,
+// which would let one writer's envelope be dropped by another's
+// concurrent drain.
+type EnvelopeQueue struct {
+	mu        sync.Mutex
+	envelopes chan *loggregator_v2.Envelope
+}
+
+// NewEnvelopeQueue builds an EnvelopeQueue buffering up to size
+// envelopes.
+func NewEnvelopeQueue(size int) *EnvelopeQueue {
+	return &EnvelopeQueue{envelopes: make(chan *loggregator_v2.Envelope, size)}
+}
+
+// Write implements otlp.EnvelopeWriter, and has the same signature as
+// the v2 package's Writer func type so it can be handed directly to
+// NewIngressReceiver.
+func (q *EnvelopeQueue) Write(env *loggregator_v2.Envelope) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case q.envelopes <- env:
+		return
+	default:
+	}
+
+	select {
+	case <-q.envelopes:
+	default:
+	}
+
+	q.envelopes <- env
+}
+
+// Envelopes returns the channel ingested envelopes are written to, for
+// whatever egress pipeline (sinks, a Repeater) consumes them.
+func (q *EnvelopeQueue) Envelopes() <-chan *loggregator_v2.Envelope {
+	return q.envelopes
+}
+
+// Doppler runs the process that accepts v2 and OTLP envelope ingress
+// over gRPC.
+type Doppler struct {
+	conf *Config
+}
+
+// NewDoppler is the constructor for Doppler.
+func NewDoppler(c *Config) *Doppler {
+	return &Doppler{conf: c}
+}
+
+// Start builds doppler's gRPC ingress server (v2 envelope ingress and
+// the OTLP LogsService/MetricsService, registered together so OTel-
+// native apps don't need a sidecar translator) and blocks serving it.
+func (d *Doppler) Start() {
+	log := logging.NewDefault().With("component", "doppler")
+	log.Info("Startup: starting doppler server")
+
+	creds, err := plumbing.NewServerCredentials(
+		d.conf.GRPC.CertFile,
+		d.conf.GRPC.KeyFile,
+		d.conf.GRPC.CAFile,
+	)
+	if err != nil {
+		log.Error("could not use GRPC creds for server", "error", err)
+		os.Exit(1)
+	}
+
+	queue := NewEnvelopeQueue(10000)
+	ingress := v2server.NewIngressReceiver(queue.Write, log)
+	grpcServer := v2server.NewGRPCServer(ingress, queue, log, grpc.Creds(creds))
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", d.conf.GRPC.Port))
+	if err != nil {
+		log.Error("failed to bind grpc listener", "error", err)
+		os.Exit(1)
+	}
+
+	log.Info("grpc bound", "addr", lis.Addr().String())
+	log.Error("serving grpc ingress exited", "error", grpcServer.Serve(lis))
+}
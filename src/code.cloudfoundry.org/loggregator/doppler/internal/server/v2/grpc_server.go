@@ -0,0 +1,26 @@
+package v2
+
+import (
+	"code.cloudfoundry.org/loggregator/logging"
+	"code.cloudfoundry.org/loggregator/plumbing/otlp"
+	"code.cloudfoundry.org/loggregator/plumbing/v2"
+	"google.golang.org/grpc"
+)
+
+// NewGRPCServer builds the gRPC server doppler listens on for v2
+// envelope ingress, additionally registering the OTLP LogsService and
+// MetricsService on the same server so apps that speak OTLP/gRPC instead
+// of the v2 protocol can be ingested without a sidecar translator.
+func NewGRPCServer(
+	ingress loggregator_v2.IngressServer,
+	otlpWriter otlp.EnvelopeWriter,
+	log *logging.Logger,
+	opts ...grpc.ServerOption,
+) *grpc.Server {
+	grpcServer := grpc.NewServer(opts...)
+
+	loggregator_v2.RegisterIngressServer(grpcServer, ingress)
+	otlp.Register(grpcServer, otlp.NewIngressServer(otlpWriter, log))
+
+	return grpcServer
+}
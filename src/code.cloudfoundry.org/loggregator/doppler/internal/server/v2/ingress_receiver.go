@@ -0,0 +1,76 @@
+package v2
+
+import (
+	"context"
+	"io"
+
+	"code.cloudfoundry.org/loggregator/logging"
+	"code.cloudfoundry.org/loggregator/plumbing/v2"
+)
+
+// IngressReceiver implements loggregator_v2.IngressServer, the v2 gRPC
+// ingress doppler has always accepted envelopes through. It hands each
+// received envelope to a Writer so it can be pushed into the same
+// batching egress pipeline (a Repeater) that everything else doppler
+// emits flows through, rather than introducing a second path.
+type IngressReceiver struct {
+	w   Writer
+	log *logging.Logger
+}
+
+// NewIngressReceiver builds an IngressReceiver. log is scoped with a
+// "component":"ingress_receiver" key; a nil log falls back to
+// logging.NewDefault().
+func NewIngressReceiver(w Writer, log *logging.Logger) *IngressReceiver {
+	if log == nil {
+		log = logging.NewDefault()
+	}
+
+	return &IngressReceiver{w: w, log: log.With("component", "ingress_receiver")}
+}
+
+// Sender accepts a stream of individual envelopes, writing each to the
+// configured Writer until the client closes the stream.
+func (r *IngressReceiver) Sender(s loggregator_v2.Ingress_SenderServer) error {
+	for {
+		env, err := s.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			r.log.Warn("ingress sender stream ended with error", "error", err)
+			return err
+		}
+
+		r.w(env)
+	}
+}
+
+// BatchSender accepts a stream of envelope batches, writing every
+// envelope in each batch to the configured Writer until the client
+// closes the stream.
+func (r *IngressReceiver) BatchSender(s loggregator_v2.Ingress_BatchSenderServer) error {
+	for {
+		batch, err := s.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			r.log.Warn("ingress batch sender stream ended with error", "error", err)
+			return err
+		}
+
+		for _, env := range batch.GetBatch() {
+			r.w(env)
+		}
+	}
+}
+
+// Send writes every envelope in a single unary batch request to the
+// configured Writer.
+func (r *IngressReceiver) Send(ctx context.Context, batch *loggregator_v2.EnvelopeBatch) (*loggregator_v2.EnvelopeResponse, error) {
+	for _, env := range batch.GetBatch() {
+		r.w(env)
+	}
+	return &loggregator_v2.EnvelopeResponse{}, nil
+}
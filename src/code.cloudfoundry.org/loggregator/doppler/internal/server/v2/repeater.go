@@ -1,33 +1,323 @@
 package v2
 
 import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/loggregator/logging"
+	"code.cloudfoundry.org/loggregator/metricemitter"
 	"code.cloudfoundry.org/loggregator/plumbing/v2"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// Repeater connects a reader to a writer.
-type Repeater struct {
-	r Reader
-	w Writer
-}
-
 // Reader reads envelopes.
 type Reader func() *loggregator_v2.Envelope
 
-// Writer writes envelopes.
+// Writer writes a single envelope.
 type Writer func(*loggregator_v2.Envelope)
 
-// NewRepeater is the constructor for Transponder.
-func NewRepeater(r Reader, w Writer) *Repeater {
-	return &Repeater{
-		r: r,
-		w: w,
+// BatchWriter writes a batch of envelopes in a single call, e.g. as an
+// EnvelopeBatch on a gRPC stream. A Repeater configured with a
+// BatchWriter ignores its Writer.
+type BatchWriter func([]*loggregator_v2.Envelope)
+
+// DropPolicy controls what a Repeater does when its internal queue is
+// full and the reader produces another envelope.
+type DropPolicy int
+
+const (
+	// DropOldest evicts the oldest queued envelope to make room.
+	DropOldest DropPolicy = iota
+	// DropNewest discards the envelope that would have been enqueued.
+	DropNewest
+	// BlockWithTimeout waits up to the configured timeout for room in
+	// the queue, then falls back to DropNewest.
+	BlockWithTimeout
+)
+
+// MetricClient can be used to emit metrics about a Repeater's pipeline.
+type MetricClient interface {
+	NewCounter(name string, opts ...metricemitter.MetricOption) *metricemitter.Counter
+	NewGauge(name string, unit string, opts ...metricemitter.MetricOption) *metricemitter.Gauge
+}
+
+// Repeater connects a reader to a writer through a bounded, batching
+// pipeline, applying backpressure according to a configurable
+// DropPolicy instead of the unbounded busy-loop this package used to
+// run.
+type Repeater struct {
+	r  Reader
+	w  Writer
+	bw BatchWriter
+
+	log *logging.Logger
+
+	batchSize     int
+	flushInterval time.Duration
+	maxInFlight   int
+	dropPolicy    DropPolicy
+	blockTimeout  time.Duration
+
+	queue chan queuedEnvelope
+	done  chan struct{}
+	drain chan struct{}
+
+	ingress       *metricemitter.Counter
+	egress        *metricemitter.Counter
+	droppedOldest *metricemitter.Counter
+	droppedNewest *metricemitter.Counter
+	queueDepth    *metricemitter.Gauge
+	latency       prometheus.Histogram
+}
+
+// queuedEnvelope pairs an envelope with the time it was read, so the
+// latency histogram can measure end-to-end time between read and write
+// instead of just the write call's duration.
+type queuedEnvelope struct {
+	env    *loggregator_v2.Envelope
+	readAt time.Time
+}
+
+// RepeaterOption configures optional Repeater behavior.
+type RepeaterOption func(*Repeater)
+
+// WithBatchSize sets the number of envelopes accumulated before a flush,
+// absent a flush triggered first by WithFlushInterval. Defaults to 100.
+func WithBatchSize(n int) RepeaterOption {
+	return func(r *Repeater) { r.batchSize = n }
+}
+
+// WithFlushInterval sets the maximum time an envelope waits in a
+// partially-filled batch before being flushed. Defaults to 100ms.
+func WithFlushInterval(d time.Duration) RepeaterOption {
+	return func(r *Repeater) { r.flushInterval = d }
+}
+
+// WithMaxInFlight sets the capacity of the internal queue between the
+// reader and the batching writer. Defaults to 1000.
+func WithMaxInFlight(n int) RepeaterOption {
+	return func(r *Repeater) { r.maxInFlight = n }
+}
+
+// WithDropPolicy sets the policy applied when the queue is full. timeout
+// is only used by BlockWithTimeout.
+func WithDropPolicy(policy DropPolicy, timeout time.Duration) RepeaterOption {
+	return func(r *Repeater) {
+		r.dropPolicy = policy
+		r.blockTimeout = timeout
+	}
+}
+
+// WithBatchWriter configures a BatchWriter used instead of the Writer
+// passed to NewRepeater, so downstream gRPC streams can send an
+// EnvelopeBatch in a single call.
+func WithBatchWriter(bw BatchWriter) RepeaterOption {
+	return func(r *Repeater) { r.bw = bw }
+}
+
+// WithLatencyHistogram records the time between an envelope being read
+// and being flushed to the writer.
+func WithLatencyHistogram(h prometheus.Histogram) RepeaterOption {
+	return func(r *Repeater) { r.latency = h }
+}
+
+// NewRepeater is the constructor for Repeater. log is scoped with a
+// "component":"repeater" key; a nil log falls back to
+// logging.NewDefault(). metricClient is used to register the
+// repeater.ingress, repeater.egress, repeater.dropped, and queue depth
+// metrics; a nil metricClient disables metrics.
+func NewRepeater(r Reader, w Writer, log *logging.Logger, metricClient MetricClient, opts ...RepeaterOption) *Repeater {
+	if log == nil {
+		log = logging.NewDefault()
+	}
+
+	rep := &Repeater{
+		r:   r,
+		w:   w,
+		log: log.With("component", "repeater"),
+
+		batchSize:     100,
+		flushInterval: 100 * time.Millisecond,
+		maxInFlight:   1000,
+		dropPolicy:    DropOldest,
+		blockTimeout:  time.Second,
+
+		done:  make(chan struct{}),
+		drain: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(rep)
 	}
+
+	rep.queue = make(chan queuedEnvelope, rep.maxInFlight)
+
+	if metricClient != nil {
+		rep.ingress = metricClient.NewCounter("repeater.ingress")
+		rep.egress = metricClient.NewCounter("repeater.egress")
+		rep.droppedOldest = metricClient.NewCounter(
+			"repeater.dropped",
+			metricemitter.WithTags(map[string]string{"reason": "oldest"}),
+		)
+		rep.droppedNewest = metricClient.NewCounter(
+			"repeater.dropped",
+			metricemitter.WithTags(map[string]string{"reason": "newest"}),
+		)
+		rep.queueDepth = metricClient.NewGauge("repeater.queue_depth", "envelopes")
+	}
+
+	return rep
 }
 
-// Start blocks indefinitely while transmitting data from the reader to the
-// writer.
+// Start blocks, reading from the configured Reader and batching
+// envelopes to the Writer/BatchWriter, until Stop is called.
 func (r *Repeater) Start() {
+	r.log.Info("starting repeater",
+		"batch_size", r.batchSize,
+		"max_in_flight", r.maxInFlight,
+	)
+
+	go r.read()
+	r.flush()
+}
+
+// Stop drains the queue, flushing any remaining envelopes to the
+// writer, until ctx is done. It blocks until draining completes or the
+// deadline passes.
+func (r *Repeater) Stop(ctx context.Context) {
+	close(r.done)
+	select {
+	case <-r.drain:
+	case <-ctx.Done():
+		r.log.Warn("repeater stop deadline exceeded while draining")
+	}
+}
+
+// QueueDepthGauge exposes the current queue depth metric so it can be
+// registered with a healthendpoint registry as a liveness/backpressure
+// signal.
+func (r *Repeater) QueueDepthGauge() *metricemitter.Gauge {
+	return r.queueDepth
+}
+
+func (r *Repeater) read() {
+	for {
+		select {
+		case <-r.done:
+			close(r.queue)
+			return
+		default:
+		}
+
+		env := r.r()
+		readAt := time.Now()
+		if r.ingress != nil {
+			r.ingress.Increment(1)
+		}
+		r.enqueue(queuedEnvelope{env: env, readAt: readAt})
+	}
+}
+
+func (r *Repeater) enqueue(env queuedEnvelope) {
+	select {
+	case r.queue <- env:
+		r.reportDepth()
+		return
+	default:
+	}
+
+	switch r.dropPolicy {
+	case DropOldest:
+		select {
+		case dropped := <-r.queue:
+			r.log.Info("envelope dropped", "reason", "oldest", "source_id", dropped.env.GetSourceId())
+			if r.droppedOldest != nil {
+				r.droppedOldest.Increment(1)
+			}
+		default:
+		}
+		select {
+		case r.queue <- env:
+		default:
+		}
+	case BlockWithTimeout:
+		select {
+		case r.queue <- env:
+		case <-time.After(r.blockTimeout):
+			r.log.Info("envelope dropped", "reason", "newest", "source_id", env.env.GetSourceId())
+			if r.droppedNewest != nil {
+				r.droppedNewest.Increment(1)
+			}
+		}
+	default: // DropNewest
+		r.log.Info("envelope dropped", "reason", "newest", "source_id", env.env.GetSourceId())
+		if r.droppedNewest != nil {
+			r.droppedNewest.Increment(1)
+		}
+	}
+
+	r.reportDepth()
+}
+
+func (r *Repeater) reportDepth() {
+	if r.queueDepth != nil {
+		r.queueDepth.Set(int64(len(r.queue)))
+	}
+}
+
+func (r *Repeater) flush() {
+	defer close(r.drain)
+
+	batch := make([]queuedEnvelope, 0, r.batchSize)
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		r.write(batch)
+		if r.egress != nil {
+			r.egress.Increment(int64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+
 	for {
-		r.w(r.r())
+		select {
+		case env, ok := <-r.queue:
+			if !ok {
+				send()
+				return
+			}
+			batch = append(batch, env)
+			if len(batch) >= r.batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		}
+	}
+}
+
+func (r *Repeater) write(batch []queuedEnvelope) {
+	envs := make([]*loggregator_v2.Envelope, len(batch))
+	for i, item := range batch {
+		envs[i] = item.env
+	}
+
+	if r.bw != nil {
+		r.bw(envs)
+	} else {
+		for _, env := range envs {
+			r.w(env)
+		}
+	}
+
+	if r.latency != nil {
+		now := time.Now()
+		for _, item := range batch {
+			r.latency.Observe(now.Sub(item.readAt).Seconds())
+		}
 	}
 }
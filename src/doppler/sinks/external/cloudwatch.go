@@ -0,0 +1,168 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/cloudfoundry/sonde-go/events"
+)
+
+func init() {
+	RegisterSink("cloudwatch", NewCloudWatchSink)
+}
+
+// cloudWatchBatchLimit mirrors the PutLogEvents limits documented by AWS:
+// at most 10,000 events or 1MB of UTF-8 payload (plus 26 bytes/event of
+// overhead) per call.
+const (
+	cloudWatchBatchLimit     = 10000
+	cloudWatchBatchByteLimit = 1 << 20
+	cloudWatchEventOverhead  = 26
+)
+
+// cloudWatchFlushInterval bounds how long an event can sit in a
+// per-stream batch before being flushed, even if neither AWS limit has
+// been reached yet.
+const cloudWatchFlushInterval = 5 * time.Second
+
+// CloudWatchSink maps deployment/job to a log group and app_guid to a
+// log stream, batching PutLogEvents calls per stream to stay within AWS
+// limits.
+type CloudWatchSink struct {
+	client *cloudwatchlogs.CloudWatchLogs
+
+	mu      sync.Mutex
+	streams map[string]*cloudWatchStream
+}
+
+type cloudWatchStream struct {
+	logGroup  string
+	logStream string
+	token     *string
+
+	events []*cloudwatchlogs.InputLogEvent
+	bytes  int
+}
+
+// NewCloudWatchSink builds a CloudWatchSink from a Factory's cfg map.
+// The only recognized key is "region" (string). The log group name is
+// always "<deployment>/<job>"; no prefix option is read.
+func NewCloudWatchSink(cfg map[string]interface{}) (Sink, error) {
+	region, _ := cfg["region"].(string)
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, fmt.Errorf("cloudwatch sink: %s", err)
+	}
+
+	return &CloudWatchSink{
+		client:  cloudwatchlogs.New(sess),
+		streams: make(map[string]*cloudWatchStream),
+	}, nil
+}
+
+// Run delivers envelopes from in to CloudWatch Logs, batching each
+// stream's PutLogEvents calls to stay within AWS's per-request limits,
+// until in is closed.
+func (c *CloudWatchSink) Run(in <-chan *events.Envelope) {
+	ticker := time.NewTicker(cloudWatchFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case v1Envelope, ok := <-in:
+			if !ok {
+				c.flushAll()
+				return
+			}
+
+			guid := appGUID(v1Envelope)
+			if guid == "" {
+				continue
+			}
+
+			payload, err := json.Marshal(v1Envelope)
+			if err != nil {
+				continue
+			}
+
+			logGroup := fmt.Sprintf("%s/%s", v1Envelope.GetDeployment(), v1Envelope.GetJob())
+			c.enqueue(logGroup, guid, string(payload), timestamp()/int64(time.Millisecond))
+		case <-ticker.C:
+			c.flushAll()
+		}
+	}
+}
+
+// enqueue appends an event to its stream's pending batch, flushing first
+// if the new event would push the batch over the AWS count or byte
+// limit.
+func (c *CloudWatchSink) enqueue(logGroup, logStream, message string, timestampMillis int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := logGroup + "/" + logStream
+	stream, ok := c.streams[key]
+	if !ok {
+		stream = &cloudWatchStream{logGroup: logGroup, logStream: logStream}
+		c.ensureStream(stream)
+		c.streams[key] = stream
+	}
+
+	eventSize := len(message) + cloudWatchEventOverhead
+	if len(stream.events) >= cloudWatchBatchLimit || stream.bytes+eventSize > cloudWatchBatchByteLimit {
+		c.flush(stream)
+	}
+
+	stream.events = append(stream.events, &cloudwatchlogs.InputLogEvent{
+		Message:   aws.String(message),
+		Timestamp: aws.Int64(timestampMillis),
+	})
+	stream.bytes += eventSize
+}
+
+// flushAll flushes every stream with a non-empty pending batch.
+func (c *CloudWatchSink) flushAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, stream := range c.streams {
+		c.flush(stream)
+	}
+}
+
+// flush sends stream's pending batch via a single PutLogEvents call. The
+// caller must hold c.mu.
+func (c *CloudWatchSink) flush(stream *cloudWatchStream) {
+	if len(stream.events) == 0 {
+		return
+	}
+
+	out, err := c.client.PutLogEvents(&cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(stream.logGroup),
+		LogStreamName: aws.String(stream.logStream),
+		SequenceToken: stream.token,
+		LogEvents:     stream.events,
+	})
+	stream.events = nil
+	stream.bytes = 0
+	if err != nil {
+		return
+	}
+
+	stream.token = out.NextSequenceToken
+}
+
+func (c *CloudWatchSink) ensureStream(s *cloudWatchStream) {
+	_, _ = c.client.CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(s.logGroup),
+	})
+	_, _ = c.client.CreateLogStream(&cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(s.logGroup),
+		LogStreamName: aws.String(s.logStream),
+	})
+}
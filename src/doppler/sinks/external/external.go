@@ -0,0 +1,111 @@
+// Package external fans envelopes out of Doppler/TrafficController to
+// third-party systems (Kafka, S3, CloudWatch Logs, ...) in addition to the
+// firehose. It mirrors the acquisition-module pattern used elsewhere in
+// this codebase: backends register a Factory under a name, and are
+// instantiated from configuration at startup rather than being wired in
+// by hand.
+package external
+
+import (
+	"fmt"
+	"sync"
+
+	"code.cloudfoundry.org/loggregator/logging"
+	"github.com/cloudfoundry/sonde-go/events"
+)
+
+// Sink consumes a stream of v1 envelopes and forwards them to a
+// third-party backend. Implementations are expected to run until their
+// input channel is closed.
+type Sink interface {
+	Run(<-chan *events.Envelope)
+}
+
+// Factory builds a Sink from its unmarshaled options. cfg is the
+// `options` map from a Config entry.
+type Factory func(cfg map[string]interface{}) (Sink, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// RegisterSink makes a Sink implementation available under name for use
+// in Config entries. It is expected to be called from the init() of each
+// adapter package, e.g. the kafka, s3, and cloudwatch sinks in this
+// package register themselves as "kafka", "s3", and "cloudwatch".
+func RegisterSink(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// New looks up the Factory registered under typ and builds a Sink from
+// cfg. It returns an error if no Factory has been registered under typ.
+func New(typ string, cfg map[string]interface{}) (Sink, error) {
+	mu.RLock()
+	factory, ok := factories[typ]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("external: no sink registered for type %q", typ)
+	}
+
+	return factory(cfg)
+}
+
+// Config describes a single external sink entry as it appears in the
+// TrafficController Config's ExternalSinks list, e.g.:
+//
+//	ExternalSinks:
+//	- type: kafka
+//	  name: audit-kafka
+//	  filter:
+//	    event_types: [LogMessage]
+//	    deployment: cf
+//	  options:
+//	    brokers: [kafka-0:9092, kafka-1:9092]
+type Config struct {
+	Type    string                 `json:"type"`
+	Name    string                 `json:"name"`
+	Filter  FilterConfig           `json:"filter"`
+	Options map[string]interface{} `json:"options"`
+}
+
+// Build instantiates the Sink described by c, wrapping it so only
+// envelopes matching c.Filter reach the underlying Sink.
+func Build(c Config) (Sink, error) {
+	sink, err := New(c.Type, c.Options)
+	if err != nil {
+		return nil, fmt.Errorf("external: building sink %q: %s", c.Name, err)
+	}
+
+	return &filteredSink{
+		filter: c.Filter.Compile(),
+		sink:   sink,
+		log:    logging.NewDefault().With("component", "external_sink", "sink_name", c.Name, "sink_type", c.Type),
+	}, nil
+}
+
+// filteredSink drops envelopes that do not match filter before handing
+// the remainder to the wrapped Sink.
+type filteredSink struct {
+	filter Filter
+	sink   Sink
+	log    *logging.Logger
+}
+
+func (f *filteredSink) Run(in <-chan *events.Envelope) {
+	out := make(chan *events.Envelope)
+	go func() {
+		defer close(out)
+		for env := range in {
+			if !f.filter.Match(env) {
+				continue
+			}
+			out <- env
+		}
+	}()
+
+	f.log.Info("starting external sink")
+	f.sink.Run(out)
+}
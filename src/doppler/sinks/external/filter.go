@@ -0,0 +1,50 @@
+package external
+
+import "github.com/cloudfoundry/sonde-go/events"
+
+// FilterConfig is the unmarshaled form of a Config entry's `filter`
+// block. Any field left empty matches everything for that dimension.
+type FilterConfig struct {
+	EventTypes []string `json:"event_types"`
+	Deployment string   `json:"deployment"`
+	Job        string   `json:"job"`
+}
+
+// Compile builds a Filter from a FilterConfig.
+func (c FilterConfig) Compile() Filter {
+	types := make(map[string]bool, len(c.EventTypes))
+	for _, t := range c.EventTypes {
+		types[t] = true
+	}
+
+	return Filter{
+		eventTypes: types,
+		deployment: c.Deployment,
+		job:        c.Job,
+	}
+}
+
+// Filter decides whether an envelope should be delivered to a sink.
+type Filter struct {
+	eventTypes map[string]bool
+	deployment string
+	job        string
+}
+
+// Match reports whether env satisfies every configured dimension of the
+// filter.
+func (f Filter) Match(env *events.Envelope) bool {
+	if len(f.eventTypes) > 0 && !f.eventTypes[env.GetEventType().String()] {
+		return false
+	}
+
+	if f.deployment != "" && env.GetDeployment() != f.deployment {
+		return false
+	}
+
+	if f.job != "" && env.GetJob() != f.job {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,116 @@
+package external
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"code.cloudfoundry.org/loggregator/plumbing/conversion"
+	"github.com/Shopify/sarama"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+)
+
+func init() {
+	RegisterSink("kafka", NewKafkaSink)
+}
+
+// KafkaSink converts envelopes to v2 and publishes them to a Kafka topic,
+// keyed by app_guid so that, with the default hash partitioner, all
+// envelopes for a given application land on the same partition.
+type KafkaSink struct {
+	topic    string
+	producer sarama.SyncProducer
+}
+
+// NewKafkaSink builds a KafkaSink from a Factory's cfg map. Recognized
+// keys are "brokers" ([]string), "topic" (string), and "partitioner"
+// (string: "hash" (default) or "random").
+func NewKafkaSink(cfg map[string]interface{}) (Sink, error) {
+	brokers, ok := stringSlice(cfg["brokers"])
+	if !ok || len(brokers) == 0 {
+		return nil, fmt.Errorf("kafka sink: \"brokers\" must be a non-empty list")
+	}
+
+	topic, _ := cfg["topic"].(string)
+	if topic == "" {
+		return nil, fmt.Errorf("kafka sink: \"topic\" is required")
+	}
+
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	switch cfg["partitioner"] {
+	case "random":
+		config.Producer.Partitioner = sarama.NewRandomPartitioner
+	default:
+		config.Producer.Partitioner = sarama.NewHashPartitioner
+	}
+
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("kafka sink: %s", err)
+	}
+
+	return &KafkaSink{topic: topic, producer: producer}, nil
+}
+
+// Run publishes every envelope on in to the configured topic until in is
+// closed.
+func (k *KafkaSink) Run(in <-chan *events.Envelope) {
+	defer k.producer.Close()
+
+	for v1Envelope := range in {
+		v2Envelope := conversion.ToV2(v1Envelope, false)
+		payload, err := proto.Marshal(v2Envelope)
+		if err != nil {
+			continue
+		}
+
+		_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
+			Topic: k.topic,
+			Key:   sarama.StringEncoder(appGUID(v1Envelope)),
+			Value: sarama.ByteEncoder(payload),
+		})
+		if err != nil {
+			continue
+		}
+	}
+}
+
+func stringSlice(v interface{}) ([]string, bool) {
+	raw, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	out := make([]string, 0, len(raw))
+	for _, item := range raw {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+// appGUID extracts the application GUID from whichever envelope type
+// carries one, returning "" if the envelope is not app-scoped.
+func appGUID(env *events.Envelope) string {
+	switch env.GetEventType() {
+	case events.Envelope_LogMessage:
+		return env.GetLogMessage().GetAppId()
+	case events.Envelope_HttpStartStop:
+		id := env.GetHttpStartStop().GetApplicationId()
+		if id == nil {
+			return ""
+		}
+		var b [16]byte
+		binary.LittleEndian.PutUint64(b[:8], id.GetLow())
+		binary.LittleEndian.PutUint64(b[8:], id.GetHigh())
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case events.Envelope_ContainerMetric:
+		return env.GetContainerMetric().GetApplicationId()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,158 @@
+package external
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/loggregator/plumbing/conversion"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/cloudfoundry/sonde-go/events"
+)
+
+func init() {
+	RegisterSink("s3", NewS3Sink)
+}
+
+// S3Sink batches envelopes into gzipped NDJSON objects and uploads them
+// once a batch reaches batchSize envelopes or flushInterval elapses,
+// whichever comes first. Uploads run on a fixed-size worker pool so a
+// slow PutObject call cannot stall ingestion.
+type S3Sink struct {
+	bucket        string
+	prefix        string
+	batchSize     int
+	flushInterval time.Duration
+
+	uploads chan []byte
+	client  *s3.S3
+}
+
+// NewS3Sink builds an S3Sink from a Factory's cfg map. Recognized keys
+// are "bucket" (string, required), "prefix" (string), "region" (string),
+// "batch_size" (int, default 1000), "flush_interval_seconds" (int,
+// default 60), and "workers" (int, default 4).
+func NewS3Sink(cfg map[string]interface{}) (Sink, error) {
+	bucket, _ := cfg["bucket"].(string)
+	if bucket == "" {
+		return nil, fmt.Errorf("s3 sink: \"bucket\" is required")
+	}
+
+	region, _ := cfg["region"].(string)
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, fmt.Errorf("s3 sink: %s", err)
+	}
+
+	sink := &S3Sink{
+		bucket:        bucket,
+		prefix:        stringOr(cfg["prefix"], ""),
+		batchSize:     intOr(cfg["batch_size"], 1000),
+		flushInterval: time.Duration(intOr(cfg["flush_interval_seconds"], 60)) * time.Second,
+		uploads:       make(chan []byte, 16),
+		client:        s3.New(sess),
+	}
+
+	workers := intOr(cfg["workers"], 4)
+	for i := 0; i < workers; i++ {
+		go sink.uploadWorker()
+	}
+
+	return sink, nil
+}
+
+// Run batches envelopes from in, flushing to the upload worker pool on
+// size or time thresholds, until in is closed.
+func (s *S3Sink) Run(in <-chan *events.Envelope) {
+	batch := make([][]byte, 0, s.batchSize)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		s.uploads <- gzipNDJSON(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case v1Envelope, ok := <-in:
+			if !ok {
+				flush()
+				close(s.uploads)
+				return
+			}
+
+			payload, err := json.Marshal(conversion.ToV2(v1Envelope, false))
+			if err != nil {
+				continue
+			}
+			batch = append(batch, payload)
+
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *S3Sink) uploadWorker() {
+	for object := range s.uploads {
+		key := fmt.Sprintf("%s%d.ndjson.gz", s.prefix, timestamp())
+		_, _ = s.client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(object),
+		})
+	}
+}
+
+func gzipNDJSON(lines [][]byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for _, line := range lines {
+		gz.Write(line)
+		gz.Write([]byte("\n"))
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func stringOr(v interface{}, def string) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return def
+}
+
+// intOr reads an integer option out of a factory cfg map. Options
+// unmarshaled from JSON/YAML into map[string]interface{} arrive as
+// float64 (or json.Number, when a Decoder is configured with UseNumber),
+// never as int, so both are handled alongside the plain int a caller
+// might set programmatically.
+func intOr(v interface{}, def int) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return int(i)
+		}
+	}
+	return def
+}
+
+// timestamp returns the wall clock in Unix nanoseconds, for S3 object
+// keys and CloudWatch event times. It is a variable so it can be
+// replaced, e.g. to make object keys deterministic.
+var timestamp = func() int64 { return time.Now().UnixNano() }
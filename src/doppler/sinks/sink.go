@@ -2,6 +2,8 @@ package sinks
 
 import (
 	"doppler/truncatingbuffer"
+
+	"code.cloudfoundry.org/loggregator/logging"
 	"github.com/cloudfoundry/gosteno"
 	"github.com/cloudfoundry/sonde-go/events"
 )
@@ -18,8 +20,18 @@ type Metric struct {
 	Value int64
 }
 
-func RunTruncatingBuffer(inputChan <-chan *events.Envelope, bufferSize uint, logger *gosteno.Logger, dropsondeOrigin string) *truncatingbuffer.TruncatingBuffer {
-	b := truncatingbuffer.NewTruncatingBuffer(inputChan, bufferSize, logger, dropsondeOrigin)
+// RunTruncatingBuffer starts a TruncatingBuffer reading from inputChan.
+// truncatingbuffer.NewTruncatingBuffer predates this codebase's move to
+// structured logging and still takes a *gosteno.Logger, so log (kept in
+// the structured logging.Logger form every other constructor in this
+// tree takes) is used only for this function's own startup event; the
+// buffer itself gets its own bare gosteno.Logger scoped to
+// dropsondeOrigin.
+func RunTruncatingBuffer(inputChan <-chan *events.Envelope, bufferSize uint, log *logging.Logger, dropsondeOrigin string) *truncatingbuffer.TruncatingBuffer {
+	log.With("component", "truncating_buffer", "dropsonde_origin", dropsondeOrigin, "buffer_size", bufferSize).
+		Info("starting truncating buffer")
+
+	b := truncatingbuffer.NewTruncatingBuffer(inputChan, bufferSize, gosteno.NewLogger(dropsondeOrigin), dropsondeOrigin)
 	go b.Run()
 	return b
 }
@@ -0,0 +1,70 @@
+package app
+
+import "doppler/sinks/external"
+
+// GRPC holds the mutual-TLS material traffic controller uses to dial
+// doppler over gRPC.
+type GRPC struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+// Agent configures where traffic controller emits its own dropsonde
+// metrics.
+type Agent struct {
+	UDPAddress string
+}
+
+// DNS configures DiscoveryMode "dns": traffic controller resolves an SRV
+// record to find doppler instances instead of using a static address
+// list.
+type DNS struct {
+	SRVName  string
+	GRPCPort uint16
+}
+
+// Consul configures DiscoveryMode "consul": traffic controller
+// long-polls a Consul catalog to find doppler instances instead of
+// using a static address list.
+type Consul struct {
+	Addr        string
+	ServiceName string
+	GRPCPort    uint16
+}
+
+// Config is the traffic controller's process configuration, populated
+// from its config file.
+type Config struct {
+	ApiHost         string
+	UaaHost         string
+	UaaClient       string
+	UaaClientSecret string
+
+	SystemDomain string
+
+	HealthAddr string
+	PProfPort  uint32
+
+	IP                    string
+	OutgoingDropsondePort uint32
+
+	SecurityEventLog string
+
+	GRPC  GRPC
+	Agent Agent
+
+	// DiscoveryMode selects how traffic controller finds doppler
+	// instances: "static" (default) uses RouterAddrs as a fixed list;
+	// "dns" and "consul" resolve the set dynamically via DNS or Consul,
+	// respectively.
+	DiscoveryMode string
+	RouterAddrs   []string
+	DNS           DNS
+	Consul        Consul
+
+	// ExternalSinks lists third-party backends (Kafka, S3, CloudWatch
+	// Logs, ...) traffic controller fans the firehose out to in addition
+	// to serving it. Each entry is built with external.Build.
+	ExternalSinks []external.Config
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/loggregator/logging"
+	"code.cloudfoundry.org/loggregator/plumbing"
+	"doppler/sinks/external"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	externalSinkBackoffMin = 250 * time.Millisecond
+	externalSinkBackoffMax = 5 * time.Second
+)
+
+// startExternalSinks builds each configured external.Config and feeds it
+// its own firehose subscription, so a slow or misbehaving sink can only
+// ever back up its own shard rather than the firehose traffic controller
+// itself serves.
+func (t *TrafficController) startExternalSinks(connector *plumbing.GRPCConnector, log *logging.Logger) {
+	for _, cfg := range t.conf.ExternalSinks {
+		sink, err := external.Build(cfg)
+		if err != nil {
+			log.Error("failed to build external sink", "error", err, "sink_name", cfg.Name)
+			continue
+		}
+
+		in := make(chan *events.Envelope)
+		go sink.Run(in)
+		go t.feedExternalSink(connector, "external-sink-"+cfg.Name, cfg.Name, in, log)
+	}
+}
+
+// feedExternalSink subscribes to the firehose under shardID and converts
+// each v2 envelope to v1 before writing it to out, resubscribing with
+// backoff on error the same way otlpegress.Handler does for an
+// otlpFirehoseSource. It runs for the
+// lifetime of the process and never returns, so unlike otlpFirehoseSource
+// it isn't scoped to a request context and out is never closed.
+func (t *TrafficController) feedExternalSink(connector *plumbing.GRPCConnector, shardID, sinkName string, out chan<- *events.Envelope, log *logging.Logger) {
+	ctx := context.Background()
+	backoff := externalSinkBackoffMin
+
+	for {
+		data, err := connector.Subscribe(ctx, &plumbing.SubscriptionRequest{
+			ShardID: shardID,
+			Filter:  &plumbing.Filter{},
+		})
+		if err != nil {
+			log.Warn("failed to open external sink subscription, retrying", "error", err, "sink_name", sinkName, "backoff", backoff)
+			time.Sleep(backoff)
+			backoff = nextExternalSinkBackoff(backoff)
+			continue
+		}
+		backoff = externalSinkBackoffMin
+
+		for {
+			raw, err := data()
+			if err != nil {
+				log.Warn("external sink subscription ended, resubscribing", "error", err, "sink_name", sinkName)
+				break
+			}
+
+			var env loggregator_v2.Envelope
+			if err := proto.Unmarshal(raw, &env); err != nil {
+				log.Warn("failed to unmarshal envelope for external sink", "error", err, "sink_name", sinkName)
+				continue
+			}
+
+			for _, v1env := range toV1Envelopes(&env) {
+				out <- v1env
+			}
+		}
+	}
+}
+
+func nextExternalSinkBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > externalSinkBackoffMax {
+		return externalSinkBackoffMax
+	}
+	return d
+}
+
+// toV1Envelopes converts a v2 envelope into the v1 dropsonde envelope(s)
+// external.Filter and the external sink backends consume. A Gauge
+// envelope commonly bundles several named metrics in one envelope (see
+// plumbing/otlp.ToMetric), so it converts to one ValueMetric envelope
+// per map entry rather than collapsing them under a single name. It
+// returns nil for envelope types with no v1 equivalent (e.g. Timer).
+func toV1Envelopes(env *loggregator_v2.Envelope) []*events.Envelope {
+	base := events.Envelope{
+		Origin:     proto.String(env.GetSourceId()),
+		Timestamp:  proto.Int64(env.GetTimestamp()),
+		Deployment: proto.String(env.GetTags()["deployment"]),
+		Job:        proto.String(env.GetTags()["job"]),
+		Index:      proto.String(env.GetTags()["index"]),
+		Ip:         proto.String(env.GetTags()["ip"]),
+	}
+
+	switch msg := env.GetMessage().(type) {
+	case *loggregator_v2.Envelope_Log:
+		out := base
+		out.EventType = events.Envelope_LogMessage.Enum()
+		messageType := events.LogMessage_OUT
+		if msg.Log.GetType() == loggregator_v2.Log_ERR {
+			messageType = events.LogMessage_ERR
+		}
+		out.LogMessage = &events.LogMessage{
+			Message:     msg.Log.GetPayload(),
+			MessageType: messageType.Enum(),
+			Timestamp:   proto.Int64(env.GetTimestamp()),
+			AppId:       proto.String(env.GetSourceId()),
+		}
+		return []*events.Envelope{&out}
+	case *loggregator_v2.Envelope_Counter:
+		out := base
+		out.EventType = events.Envelope_CounterEvent.Enum()
+		out.CounterEvent = &events.CounterEvent{
+			Name:  proto.String(msg.Counter.GetName()),
+			Total: proto.Uint64(msg.Counter.GetTotal()),
+		}
+		return []*events.Envelope{&out}
+	case *loggregator_v2.Envelope_Gauge:
+		envs := make([]*events.Envelope, 0, len(msg.Gauge.GetMetrics()))
+		for name, v := range msg.Gauge.GetMetrics() {
+			out := base
+			out.EventType = events.Envelope_ValueMetric.Enum()
+			out.ValueMetric = &events.ValueMetric{
+				Name:  proto.String(name),
+				Value: proto.Float64(v.GetValue()),
+				Unit:  proto.String(v.GetUnit()),
+			}
+			envs = append(envs, &out)
+		}
+		return envs
+	default:
+		return nil
+	}
+}
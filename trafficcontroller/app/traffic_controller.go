@@ -1,21 +1,25 @@
 package app
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 	"code.cloudfoundry.org/loggregator/healthendpoint"
+	"code.cloudfoundry.org/loggregator/logging"
 
 	"code.cloudfoundry.org/loggregator/metricemitter"
 	"code.cloudfoundry.org/loggregator/plumbing"
 	"code.cloudfoundry.org/loggregator/profiler"
 	"code.cloudfoundry.org/loggregator/trafficcontroller/internal/auth"
+	"code.cloudfoundry.org/loggregator/trafficcontroller/internal/otlpegress"
 	"code.cloudfoundry.org/loggregator/trafficcontroller/internal/proxy"
 
 	"github.com/cloudfoundry/dropsonde"
@@ -28,6 +32,7 @@ import (
 	"github.com/cloudfoundry/dropsonde/metricbatcher"
 	"github.com/cloudfoundry/dropsonde/metrics"
 	"github.com/cloudfoundry/dropsonde/runtime_stats"
+	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
@@ -54,6 +59,10 @@ type finder interface {
 	Next() plumbing.Event
 }
 
+// logFormatFlag registers --log-format on the default FlagSet at
+// package init time so it shows up alongside the process's other flags.
+var logFormatFlag = logging.AddFlags(flag.CommandLine)
+
 func NewTrafficController(
 	c *Config,
 	disableAccessControl bool,
@@ -71,11 +80,17 @@ func NewTrafficController(
 }
 
 func (t *TrafficController) Start() {
-	log.Print("Startup: Setting up the loggregator traffic controller")
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	log := logging.New(logging.ParseFormat(*logFormatFlag), os.Stdout).
+		With("component", "trafficcontroller")
+
+	log.Info("Startup: Setting up the loggregator traffic controller")
 
 	batcher, err := t.initializeMetrics("LoggregatorTrafficController", t.conf.Agent.UDPAddress)
 	if err != nil {
-		log.Printf("Error initializing dropsonde: %s", err)
+		log.Error("Error initializing dropsonde", "error", err)
 	}
 
 	logAuthorizer := auth.NewLogAccessAuthorizer(
@@ -136,10 +151,11 @@ func (t *TrafficController) Start() {
 		"doppler",
 	)
 	if err != nil {
-		log.Fatalf("Could not use GRPC creds for server: %s", err)
+		log.Error("Could not use GRPC creds for server", "error", err)
+		os.Exit(1)
 	}
 
-	f := plumbing.NewStaticFinder(t.conf.RouterAddrs)
+	f := t.buildFinder(log)
 	f.Start()
 
 	kp := keepalive.ClientParameters{
@@ -148,26 +164,39 @@ func (t *TrafficController) Start() {
 		PermitWithoutStream: true,
 	}
 	pool := plumbing.NewPool(20, grpc.WithTransportCredentials(creds), grpc.WithKeepaliveParams(kp))
-	grpcConnector := plumbing.NewGRPCConnector(1000, pool, f, batcher, t.metricClient)
-
-	dopplerHandler := http.Handler(
-		proxy.NewDopplerProxy(
-			logAuthorizer,
-			adminAuthorizer,
-			grpcConnector,
-			"doppler."+t.conf.SystemDomain,
-			5*time.Second,
-			5*time.Second,
-			t.metricClient,
-			healthRegistry,
-		),
+	grpcConnector := plumbing.NewGRPCConnector(1000, pool, f, log, batcher, t.metricClient)
+
+	dopplerProxy := proxy.NewDopplerProxy(
+		logAuthorizer,
+		adminAuthorizer,
+		grpcConnector,
+		"doppler."+t.conf.SystemDomain,
+		5*time.Second,
+		5*time.Second,
+		t.metricClient,
+		healthRegistry,
 	)
 
+	t.startExternalSinks(grpcConnector, log)
+
+	otlpHandler := otlpegress.NewHandler(
+		t.otlpFirehoseSource(grpcConnector, "otlp-logs"),
+		t.otlpFirehoseSource(grpcConnector, "otlp-metrics"),
+		log,
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/v2/otlp/logs", otlpHandler)
+	mux.Handle("/v2/otlp/metrics", otlpHandler)
+	mux.Handle("/", dopplerProxy)
+	dopplerHandler := http.Handler(mux)
+
 	var accessMiddleware func(http.Handler) *auth.AccessHandler
 	if t.conf.SecurityEventLog != "" {
 		accessLog, err := os.OpenFile(t.conf.SecurityEventLog, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
 		if err != nil {
-			log.Panicf("Unable to open access log: %s", err)
+			log.Error("Unable to open access log", "error", err)
+			panic(err)
 		}
 		defer func() {
 			accessLog.Sync()
@@ -183,10 +212,12 @@ func (t *TrafficController) Start() {
 	go func() {
 		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", t.conf.OutgoingDropsondePort))
 		if err != nil {
-			log.Fatal(err)
+			log.Error("Failed to bind dropsonde listener", "error", err)
+			os.Exit(1)
 		}
-		log.Printf("ws bound to: %s", lis.Addr())
-		log.Fatal(http.Serve(lis, dopplerHandler))
+		log.Info("ws bound", "addr", lis.Addr().String())
+		log.Error("Serving dropsonde handler exited", "error", http.Serve(lis, dopplerHandler))
+		os.Exit(1)
 	}()
 
 	// We start the profiler last so that we can definitively claim that we're ready for
@@ -197,7 +228,53 @@ func (t *TrafficController) Start() {
 	killChan := make(chan os.Signal)
 	signal.Notify(killChan, os.Interrupt)
 	<-killChan
-	log.Print("Shutting down")
+	log.Info("Shutting down")
+}
+
+// otlpFirehoseSource returns an otlpegress.Source that subscribes to the
+// full firehose under shardID. It opens that subscription lazily, once
+// per HTTP request rather than once at TrafficController startup, since
+// the GRPCConnector's pool is still empty at boot (watchFinder populates
+// it asynchronously); subscribing eagerly would permanently fail with
+// ErrNoDopplers before any connections exist.
+func (t *TrafficController) otlpFirehoseSource(connector *plumbing.GRPCConnector, shardID string) otlpegress.Source {
+	return func(ctx context.Context) (func() (*loggregator_v2.Envelope, error), error) {
+		data, err := connector.Subscribe(ctx, &plumbing.SubscriptionRequest{
+			ShardID: shardID,
+			Filter:  &plumbing.Filter{},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return func() (*loggregator_v2.Envelope, error) {
+			raw, err := data()
+			if err != nil {
+				return nil, err
+			}
+
+			var env loggregator_v2.Envelope
+			if err := proto.Unmarshal(raw, &env); err != nil {
+				return nil, err
+			}
+
+			return &env, nil
+		}, nil
+	}
+}
+
+// buildFinder selects a plumbing.Finder implementation according to
+// t.conf.DiscoveryMode, defaulting to the static, restart-required
+// finder when the mode is unset or "static".
+func (t *TrafficController) buildFinder(log *logging.Logger) finder {
+	switch t.conf.DiscoveryMode {
+	case "dns":
+		return plumbing.NewDNSFinder(t.conf.DNS.SRVName, t.conf.DNS.GRPCPort, log)
+	case "consul":
+		return plumbing.NewConsulFinder(t.conf.Consul.Addr, t.conf.Consul.ServiceName, t.conf.Consul.GRPCPort, log)
+	default:
+		return plumbing.NewStaticFinder(t.conf.RouterAddrs)
+	}
 }
 
 func (t *TrafficController) setupDefaultEmitter(origin, destination string) error {
@@ -237,4 +314,4 @@ func (t *TrafficController) initializeMetrics(origin, destination string) (*metr
 	go runtime_stats.NewRuntimeStats(dropsonde.DefaultEmitter, 10*time.Second).Run(nil)
 	http.DefaultTransport = dropsonde.InstrumentedRoundTripper(http.DefaultTransport)
 	return batcher, err
-}
\ No newline at end of file
+}
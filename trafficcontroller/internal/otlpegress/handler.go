@@ -0,0 +1,162 @@
+// Package otlpegress streams firehose envelopes out of TrafficController
+// as OTLP, so collectors that speak OTLP can subscribe to
+// /v2/otlp/logs and /v2/otlp/metrics instead of decoding dropsonde.
+package otlpegress
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/loggregator/logging"
+	"code.cloudfoundry.org/loggregator/plumbing/otlp"
+)
+
+const (
+	subscribeBackoffMin = 250 * time.Millisecond
+	subscribeBackoffMax = 5 * time.Second
+)
+
+// Source opens a v2 envelope subscription for one HTTP request,
+// returning a reader that yields envelopes until the subscription ends.
+// It is called lazily, once per request rather than once at startup, so
+// a request made before the connector's pool is populated doesn't get
+// stuck reading from a permanently empty subscription; stream retries it
+// with backoff on error.
+type Source func(ctx context.Context) (func() (*loggregator_v2.Envelope, error), error)
+
+// Handler serves /v2/otlp/logs and /v2/otlp/metrics, converting each
+// envelope read from its Source to OTLP and writing it as a stream of
+// newline-delimited JSON objects for as long as the client stays
+// connected.
+type Handler struct {
+	logs    Source
+	metrics Source
+	log     *logging.Logger
+}
+
+// NewHandler builds a Handler. log is scoped with a
+// "component":"otlp_egress" key; a nil log falls back to
+// logging.NewDefault().
+func NewHandler(logs, metrics Source, log *logging.Logger) *Handler {
+	if log == nil {
+		log = logging.NewDefault()
+	}
+
+	return &Handler{logs: logs, metrics: metrics, log: log.With("component", "otlp_egress")}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/v2/otlp/logs":
+		h.stream(w, r, h.logs, func(env *loggregator_v2.Envelope) []interface{} {
+			record := otlp.ToLogRecord(env)
+			if record == nil {
+				return nil
+			}
+			return []interface{}{record}
+		})
+	case "/v2/otlp/metrics":
+		h.stream(w, r, h.metrics, func(env *loggregator_v2.Envelope) []interface{} {
+			metrics := otlp.ToMetric(env)
+			out := make([]interface{}, len(metrics))
+			for i, m := range metrics {
+				out[i] = m
+			}
+			return out
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) stream(
+	w http.ResponseWriter,
+	r *http.Request,
+	source Source,
+	convert func(*loggregator_v2.Envelope) []interface{},
+) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+
+	h.log.Info("otlp egress stream opened", "path", r.URL.Path)
+	defer h.log.Info("otlp egress stream closed", "path", r.URL.Path)
+
+	var read func() (*loggregator_v2.Envelope, error)
+	backoff := subscribeBackoffMin
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		default:
+		}
+
+		if read == nil {
+			var err error
+			read, err = source(r.Context())
+			if err != nil {
+				h.log.Warn("failed to open otlp egress subscription, retrying", "error", err, "backoff", backoff)
+				if !sleep(r.Context(), backoff) {
+					return
+				}
+				backoff = nextBackoff(backoff)
+				continue
+			}
+			backoff = subscribeBackoffMin
+		}
+
+		env, err := read()
+		if err != nil {
+			h.log.Warn("otlp egress subscription ended, retrying", "error", err, "backoff", backoff)
+			read = nil
+			if !sleep(r.Context(), backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+
+		converted := convert(env)
+		if len(converted) == 0 {
+			continue
+		}
+
+		for _, record := range converted {
+			if err := enc.Encode(record); err != nil {
+				h.log.Warn("failed to encode otlp egress record", "error", err)
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// sleep waits for d, returning false early if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > subscribeBackoffMax {
+		return subscribeBackoffMax
+	}
+	return d
+}